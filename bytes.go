@@ -0,0 +1,61 @@
+package logrus_fluent
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"reflect"
+)
+
+// BytesEncoding is encoding type for byte slices and byte arrays.
+type BytesEncoding int
+
+const (
+	// BytesBinary encodes bytes to msgpack binary. (default)
+	BytesBinary BytesEncoding = iota
+	// BytesBase64 encodes bytes to base64 string with standard encoding.
+	BytesBase64
+	// BytesHex encodes bytes to lowercase hex string.
+	BytesHex
+)
+
+// isBytes checks the value is a byte slice or a byte array.
+func isBytes(rv reflect.Value) bool {
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		return rv.Type().Elem().Kind() == reflect.Uint8
+	}
+	return false
+}
+
+// encodeBytes converts a byte slice or a byte array by BytesEncoding.
+func (c Converter) encodeBytes(rv reflect.Value) interface{} {
+	if rv.Kind() == reflect.Slice && rv.IsNil() {
+		return nil
+	}
+
+	// copy bytes not to be changed after the log is fired.
+	b := make([]byte, rv.Len())
+	if rv.Kind() == reflect.Slice && rv.Type().Elem() == byteType {
+		copy(b, rv.Bytes())
+	} else {
+		for i := range b {
+			b[i] = byte(rv.Index(i).Uint())
+		}
+	}
+
+	switch c.BytesEncoding {
+	case BytesBase64:
+		return base64.StdEncoding.EncodeToString(b)
+	case BytesHex:
+		return hex.EncodeToString(b)
+	default:
+		return b
+	}
+}
+
+var byteType = reflect.TypeOf(byte(0))
+
+// SetBytesEncoding sets encoding type for byte slices and byte arrays.
+func (hook *FluentHook) SetBytesEncoding(enc BytesEncoding) {
+	hook.converter.BytesEncoding = enc
+}
@@ -0,0 +1,33 @@
+package logrus_fluent
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetBytesEncoding(t *testing.T) {
+	a := assert.New(t)
+
+	hook := NewHook(testHOST, -1)
+	entry := logrus.NewEntry(logrus.New())
+	entry.Data = logrus.Fields{"tag": fieldTag, "body": []byte("body"), "hash": [2]byte{0xca, 0xfe}}
+
+	tests := []struct {
+		enc  BytesEncoding
+		body interface{}
+		hash interface{}
+	}{
+		{BytesBinary, []byte("body"), []byte{0xca, 0xfe}},
+		{BytesBase64, "Ym9keQ==", "yv4="},
+		{BytesHex, "626f6479", "cafe"},
+	}
+	for _, tt := range tests {
+		hook.SetBytesEncoding(tt.enc)
+		_, data := hook.getTagAndData(entry)
+		result := hook.encodeData(data).(map[string]interface{})
+		a.Equal(tt.body, result["body"], "%d", tt.enc)
+		a.Equal(tt.hash, result["hash"], "%d", tt.enc)
+	}
+}
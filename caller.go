@@ -0,0 +1,173 @@
+package logrus_fluent
+
+import (
+	"go/build"
+	"path"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// default field names for the caller.
+const (
+	CallerFileKey     = "file"
+	CallerLineKey     = "line"
+	CallerFunctionKey = "func"
+)
+
+// CallerConfig is settings for the caller information.
+// The caller is added only when logger.SetReportCaller(true) is used.
+type CallerConfig struct {
+	FileKey     string // default is "file".
+	LineKey     string // default is "line".
+	FunctionKey string // default is "func".
+
+	Levels         []logrus.Level // caller is added only for these levels. all levels if empty.
+	TrimModuleRoot bool           // trim the source directory of the main module from the file path.
+	TrimGOPATH     bool           // trim "$GOPATH/src/" and "$GOPATH/pkg/mod/" from the file path.
+	TrimPrefixes   []string       // trim these prefixes from the file path.
+}
+
+// LevelThreshold returns the levels at or above the threshold.
+//
+//	LevelThreshold(logrus.WarnLevel) => [panic, fatal, error, warning]
+func LevelThreshold(threshold logrus.Level) []logrus.Level {
+	var levels []logrus.Level
+	for _, l := range logrus.AllLevels {
+		if l <= threshold {
+			levels = append(levels, l)
+		}
+	}
+	return levels
+}
+
+// SetCaller sets settings for the caller information.
+// The caller is not added when conf is nil.
+func (hook *FluentHook) SetCaller(conf *CallerConfig) {
+	if conf == nil {
+		hook.caller = nil
+		return
+	}
+
+	c := *conf
+	if c.FileKey == "" {
+		c.FileKey = CallerFileKey
+	}
+	if c.LineKey == "" {
+		c.LineKey = CallerLineKey
+	}
+	if c.FunctionKey == "" {
+		c.FunctionKey = CallerFunctionKey
+	}
+
+	prefixes := append([]string{}, c.TrimPrefixes...)
+	if c.TrimGOPATH {
+		for _, dir := range filepath.SplitList(build.Default.GOPATH) {
+			prefixes = append(prefixes,
+				filepath.Join(dir, "src")+string(filepath.Separator),
+				filepath.Join(dir, "pkg", "mod")+string(filepath.Separator),
+			)
+		}
+	}
+	c.TrimPrefixes = prefixes
+	hook.caller = &c
+}
+
+// setCaller adds the caller information into data fields.
+func (hook *FluentHook) setCaller(entry *logrus.Entry, data logrus.Fields) {
+	c := hook.caller
+	if c == nil || entry.Caller == nil {
+		return
+	}
+	if len(c.Levels) != 0 && !hasLevel(c.Levels, entry.Level) {
+		return
+	}
+
+	file := entry.Caller.File
+	if c.TrimModuleRoot {
+		file = trimModuleRoot(file, entry.Caller.Function)
+	}
+	data[c.FileKey] = trimPrefixes(file, c.TrimPrefixes)
+	data[c.LineKey] = entry.Caller.Line
+	data[c.FunctionKey] = entry.Caller.Function
+}
+
+func hasLevel(levels []logrus.Level, level logrus.Level) bool {
+	for _, l := range levels {
+		if l == level {
+			return true
+		}
+	}
+	return false
+}
+
+// trimPrefixes removes the first matched prefix from the path.
+func trimPrefixes(path string, prefixes []string) string {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(path, prefix) {
+			return strings.TrimPrefix(path, prefix)
+		}
+	}
+	return path
+}
+
+// mainModule is the module path and the main package path in the build info.
+var mainModule struct {
+	once    sync.Once
+	path    string // e.g. "github.com/foo/app"
+	pkgPath string // e.g. "github.com/foo/app/cmd/server"
+}
+
+// trimModuleRoot trims the source directory of the main module from the file path of the caller.
+// The file paths are recorded at compile time, so the directory is found from the package path of the function
+// instead of the working directory, and it works for the binary deployed outside of the source tree.
+func trimModuleRoot(file, function string) string {
+	mainModule.once.Do(func() {
+		if info, ok := debug.ReadBuildInfo(); ok {
+			mainModule.path = info.Main.Path
+			mainModule.pkgPath = info.Path
+		}
+	})
+	return trimModuleRootOf(file, function, mainModule.path, mainModule.pkgPath)
+}
+
+// trimModuleRootOf trims the module root from the file when the function is in the module.
+//
+//	("/src/app/pkg/sub/file.go", "github.com/foo/app/pkg/sub.Func", "github.com/foo/app") => "pkg/sub/file.go"
+func trimModuleRootOf(file, function, modulePath, mainPkgPath string) string {
+	if modulePath == "" {
+		return file
+	}
+
+	pkgPath := funcPackagePath(function)
+	if pkgPath == "main" {
+		pkgPath = mainPkgPath // package main is not qualified in the function name.
+	}
+	if pkgPath != modulePath && !strings.HasPrefix(pkgPath, modulePath+"/") {
+		return file // not in the main module
+	}
+
+	// the directory of the package in the module. (e.g. "/pkg/sub")
+	rel := strings.TrimPrefix(pkgPath, modulePath)
+	dir := path.Dir(file)
+	if !strings.HasSuffix(dir, rel) {
+		return file
+	}
+	root := strings.TrimSuffix(dir, rel) + "/"
+	return strings.TrimPrefix(file, root)
+}
+
+// funcPackagePath returns the package path of the function name.
+//
+//	"github.com/foo/app/pkg.(*T).Method" => "github.com/foo/app/pkg"
+func funcPackagePath(function string) string {
+	slash := strings.LastIndex(function, "/")
+	dot := strings.Index(function[slash+1:], ".")
+	if dot < 0 {
+		return function
+	}
+	return function[:slash+1+dot]
+}
@@ -0,0 +1,124 @@
+package logrus_fluent
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLevelThreshold(t *testing.T) {
+	a := assert.New(t)
+
+	a.Equal([]logrus.Level{logrus.PanicLevel}, LevelThreshold(logrus.PanicLevel))
+	a.Equal([]logrus.Level{
+		logrus.PanicLevel,
+		logrus.FatalLevel,
+		logrus.ErrorLevel,
+		logrus.WarnLevel,
+	}, LevelThreshold(logrus.WarnLevel))
+	a.Equal(logrus.AllLevels, LevelThreshold(logrus.TraceLevel))
+}
+
+func TestTrimPrefixes(t *testing.T) {
+	a := assert.New(t)
+
+	prefixes := []string{"/home/user/app/", "/go/src/"}
+	a.Equal("pkg/main.go", trimPrefixes("/home/user/app/pkg/main.go", prefixes))
+	a.Equal("github.com/foo/bar/bar.go", trimPrefixes("/go/src/github.com/foo/bar/bar.go", prefixes))
+	a.Equal("/opt/main.go", trimPrefixes("/opt/main.go", prefixes))
+}
+
+func TestSetCaller(t *testing.T) {
+	a := assert.New(t)
+
+	wd, err := os.Getwd()
+	a.NoError(err)
+	_, file, _, _ := runtime.Caller(0)
+
+	hook := NewHook(testHOST, -1)
+	hook.SetCaller(&CallerConfig{
+		FunctionKey:  "function",
+		Levels:       LevelThreshold(logrus.WarnLevel),
+		TrimPrefixes: []string{wd + string(filepath.Separator)},
+	})
+
+	entry := logrus.NewEntry(logrus.New())
+	entry.Data = logrus.Fields{"tag": fieldTag}
+	entry.Level = logrus.ErrorLevel
+	entry.Caller = &runtime.Frame{
+		File:     file,
+		Line:     10,
+		Function: "main.main",
+	}
+
+	_, data := hook.getTagAndData(entry)
+	a.Equal(filepath.Base(file), data[CallerFileKey])
+	a.Equal(10, data[CallerLineKey])
+	a.Equal("main.main", data["function"])
+	a.NotContains(data, CallerFunctionKey)
+
+	// below the threshold
+	entry.Level = logrus.InfoLevel
+	_, data = hook.getTagAndData(entry)
+	a.NotContains(data, CallerFileKey)
+
+	// caller is not reported
+	entry.Level = logrus.ErrorLevel
+	entry.Caller = nil
+	_, data = hook.getTagAndData(entry)
+	a.NotContains(data, CallerFileKey)
+
+	hook.SetCaller(nil)
+	a.Nil(hook.caller)
+}
+
+func TestTrimModuleRootOf(t *testing.T) {
+	a := assert.New(t)
+
+	const module = "github.com/foo/app"
+	tests := []struct {
+		file     string
+		function string
+		expected string
+	}{
+		{"/build/app/pkg/sub/file.go", "github.com/foo/app/pkg/sub.Func", "pkg/sub/file.go"},
+		{"/build/app/pkg/sub/file.go", "github.com/foo/app/pkg/sub.(*T).Method", "pkg/sub/file.go"},
+		{"/build/app/file.go", "github.com/foo/app.Func", "file.go"},
+		{"/build/app/cmd/server/main.go", "main.main", "cmd/server/main.go"},
+		{"github.com/foo/app/pkg/file.go", "github.com/foo/app/pkg.Func", "pkg/file.go"}, // -trimpath
+		{"/go/pkg/mod/github.com/bar/lib@v1.0.0/lib.go", "github.com/bar/lib.Func", "/go/pkg/mod/github.com/bar/lib@v1.0.0/lib.go"},
+		{"/build/app2/file.go", "github.com/foo/app2.Func", "/build/app2/file.go"},
+		{"/build/other/file.go", "github.com/foo/app/pkg.Func", "/build/other/file.go"},
+	}
+	for _, tt := range tests {
+		a.Equal(tt.expected, trimModuleRootOf(tt.file, tt.function, module, module+"/cmd/server"), tt.function)
+	}
+
+	// no build info
+	a.Equal("/build/app/file.go", trimModuleRootOf("/build/app/file.go", "github.com/foo/app.Func", "", ""))
+}
+
+func TestSetCallerTrimModuleRoot(t *testing.T) {
+	a := assert.New(t)
+
+	info, ok := debug.ReadBuildInfo()
+	if !ok || info.Main.Path == "" {
+		t.Skip("no module information in the build info")
+	}
+
+	hook := NewHook(testHOST, -1)
+	hook.SetCaller(&CallerConfig{TrimModuleRoot: true})
+
+	pc, file, line, _ := runtime.Caller(0)
+	entry := logrus.NewEntry(logrus.New())
+	entry.Data = logrus.Fields{"tag": fieldTag}
+	entry.Caller = &runtime.Frame{File: file, Line: line, Function: runtime.FuncForPC(pc).Name()}
+
+	_, data := hook.getTagAndData(entry)
+	a.Equal("caller_test.go", data[CallerFileKey])
+}
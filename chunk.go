@@ -0,0 +1,345 @@
+package logrus_fluent
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// field names for the chunked records.
+const (
+	ChunkIDField     = "chunk_id"
+	ChunkIndexField  = "chunk_index"
+	ChunkTotalField  = "chunk_total"
+	ChunkFieldsField = "chunk_fields"
+)
+
+// ErrIncompleteChunks is returned by Reassemble when some chunks are missing.
+var ErrIncompleteChunks = errors.New("logrus_fluent: incomplete chunks")
+
+// ErrInvalidChunks is returned by Reassemble when the chunks can't be merged.
+var ErrInvalidChunks = errors.New("logrus_fluent: invalid chunks")
+
+// ChunkConfig is settings for splitting a record with a large field into several records.
+// The other fields are copied into every chunk, and the split fields are listed in ChunkFieldsField.
+//
+//	{"message": "aaaabbbbcc"} (MaxSize: 4)
+//	=> {"message": "aaaa", "chunk_id": "...", "chunk_index": 0, "chunk_total": 3, "chunk_fields": ["message"]}
+//	   {"message": "bbbb", "chunk_id": "...", "chunk_index": 1, "chunk_total": 3, "chunk_fields": ["message"]}
+//	   {"message": "cc",   "chunk_id": "...", "chunk_index": 2, "chunk_total": 3, "chunk_fields": ["message"]}
+type ChunkConfig struct {
+	MaxSize int      // max bytes of a string or a byte slice in a chunk. chunking is disabled if 0.
+	Fields  []string // top-level fields split into chunks. default is the message field.
+}
+
+// fields returns the fields split into chunks.
+func (c ChunkConfig) fields(messageField string) []string {
+	if len(c.Fields) != 0 {
+		return c.Fields
+	}
+	return []string{messageField}
+}
+
+// split splits the data into chunks when the fields exceed MaxSize.
+// The data is returned as it is when no field exceeds MaxSize.
+func (c ChunkConfig) split(data map[string]interface{}, fields []string) []map[string]interface{} {
+	if c.MaxSize <= 0 {
+		return []map[string]interface{}{data}
+	}
+
+	parts := make(map[string][]interface{})
+	var split []string
+	total := 1
+	for _, f := range fields {
+		p := splitValue(data[f], c.MaxSize)
+		if p == nil {
+			continue
+		}
+		if _, ok := parts[f]; !ok {
+			split = append(split, f)
+		}
+		parts[f] = p
+		if len(p) > total {
+			total = len(p)
+		}
+	}
+	if total == 1 {
+		return []map[string]interface{}{data}
+	}
+
+	id := newChunkID()
+	chunks := make([]map[string]interface{}, total)
+	for i := range chunks {
+		m := make(map[string]interface{}, len(data)+4)
+		for k, v := range data {
+			m[k] = v
+		}
+		for f, p := range parts {
+			switch {
+			case i < len(p):
+				m[f] = p[i]
+			case isBytesValue(p[0]):
+				m[f] = []byte{}
+			default:
+				m[f] = ""
+			}
+		}
+		m[ChunkIDField] = id
+		m[ChunkIndexField] = i
+		m[ChunkTotalField] = total
+		m[ChunkFieldsField] = split
+		chunks[i] = m
+	}
+	return chunks
+}
+
+// splitValue splits a string or a byte slice into parts of max bytes.
+// It returns nil when the value is not split.
+func splitValue(v interface{}, max int) []interface{} {
+	switch vv := v.(type) {
+	case string:
+		if len(vv) <= max {
+			return nil
+		}
+		var parts []interface{}
+		for len(vv) > max {
+			// not to break a multibyte character
+			n := max
+			for n > 0 && !utf8.RuneStart(vv[n]) {
+				n--
+			}
+			if n == 0 {
+				_, n = utf8.DecodeRuneInString(vv)
+			}
+			parts = append(parts, vv[:n])
+			vv = vv[n:]
+		}
+		if vv == "" {
+			return parts
+		}
+		return append(parts, vv)
+	case []byte:
+		if len(vv) <= max {
+			return nil
+		}
+		var parts []interface{}
+		for len(vv) > max {
+			parts = append(parts, vv[:max])
+			vv = vv[max:]
+		}
+		return append(parts, vv)
+	}
+	return nil
+}
+
+func isBytesValue(v interface{}) bool {
+	_, ok := v.([]byte)
+	return ok
+}
+
+// newChunkID returns a random id shared by the chunks.
+func newChunkID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(b)
+}
+
+// chunkGroup is the chunks of a record.
+type chunkGroup struct {
+	total    int
+	chunks   map[int]map[string]interface{}
+	received []map[string]interface{} // in the received order, for incomplete chunks.
+}
+
+// complete checks all of the chunks are received.
+func (g *chunkGroup) complete() bool {
+	if g.total <= 0 || len(g.chunks) != g.total {
+		return false
+	}
+	for i := 0; i < g.total; i++ {
+		if _, ok := g.chunks[i]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// merge concatenates the split fields of the chunks in order.
+// The split fields are read from ChunkFieldsField of the first chunk.
+func (g *chunkGroup) merge() (map[string]interface{}, error) {
+	first := g.chunks[0]
+	fields, ok := chunkFields(first[ChunkFieldsField])
+	if !ok {
+		return nil, fmt.Errorf("%s is not found", ChunkFieldsField)
+	}
+
+	result := make(map[string]interface{}, len(first))
+	for k, v := range first {
+		switch k {
+		case ChunkIDField, ChunkIndexField, ChunkTotalField, ChunkFieldsField:
+			continue
+		}
+		result[k] = v
+	}
+
+	for _, f := range fields {
+		var buf bytes.Buffer
+		isBytes := isBytesValue(first[f])
+		for i := 0; i < g.total; i++ {
+			switch v := g.chunks[i][f].(type) {
+			case string:
+				buf.WriteString(v)
+			case []byte:
+				buf.Write(v)
+			default:
+				return nil, fmt.Errorf("field %s of chunk %d is not a string", f, i)
+			}
+		}
+		if isBytes {
+			result[f] = buf.Bytes()
+		} else {
+			result[f] = buf.String()
+		}
+	}
+	return result, nil
+}
+
+// Reassemble merges the chunks in the decoded records into the original records.
+// The records are returned in the order of the first chunk, and the records which are not chunked are returned as they are.
+// The chunks of incomplete records are returned as they are with ErrIncompleteChunks,
+// and the chunks which can't be merged are returned as they are with ErrInvalidChunks.
+// The split fields are read from the chunks, so the config doesn't need to be the same as the hook's config.
+func (c ChunkConfig) Reassemble(records []map[string]interface{}) ([]map[string]interface{}, error) {
+	type slot struct {
+		record map[string]interface{}
+		id     string // chunk id if the slot is for chunks.
+	}
+
+	slots := make([]slot, 0, len(records))
+	groups := make(map[string]*chunkGroup)
+	for _, r := range records {
+		id, ok1 := chunkID(r[ChunkIDField])
+		index, ok2 := chunkInt(r[ChunkIndexField])
+		total, ok3 := chunkInt(r[ChunkTotalField])
+		if !ok1 || !ok2 || !ok3 {
+			slots = append(slots, slot{record: r})
+			continue
+		}
+
+		g, ok := groups[id]
+		if !ok {
+			g = &chunkGroup{total: total, chunks: make(map[int]map[string]interface{})}
+			groups[id] = g
+			slots = append(slots, slot{id: id})
+		}
+		g.chunks[index] = r
+		g.received = append(g.received, r)
+	}
+
+	result := make([]map[string]interface{}, 0, len(slots))
+	var incomplete, invalid []string
+	for _, s := range slots {
+		if s.id == "" {
+			result = append(result, s.record)
+			continue
+		}
+		g := groups[s.id]
+		if !g.complete() {
+			incomplete = append(incomplete, s.id)
+			result = append(result, g.received...)
+			continue
+		}
+		merged, err := g.merge()
+		if err != nil {
+			invalid = append(invalid, s.id+": "+err.Error())
+			result = append(result, g.received...)
+			continue
+		}
+		result = append(result, merged)
+	}
+	if len(invalid) != 0 {
+		return result, fmt.Errorf("%w: %s", ErrInvalidChunks, strings.Join(invalid, ", "))
+	}
+	if len(incomplete) != 0 {
+		return result, fmt.Errorf("%w: %s", ErrIncompleteChunks, strings.Join(incomplete, ","))
+	}
+	return result, nil
+}
+
+// chunkID returns the chunk id in the decoded record. msgpack str can be decoded as []byte.
+func chunkID(v interface{}) (string, bool) {
+	switch vv := v.(type) {
+	case string:
+		return vv, vv != ""
+	case []byte:
+		return string(vv), len(vv) != 0
+	}
+	return "", false
+}
+
+// chunkFields returns the split fields in the decoded record.
+func chunkFields(v interface{}) ([]string, bool) {
+	var items []interface{}
+	switch vv := v.(type) {
+	case []string:
+		return vv, len(vv) != 0
+	case []interface{}:
+		items = vv
+	default:
+		return nil, false
+	}
+
+	fields := make([]string, len(items))
+	for i, item := range items {
+		f, ok := chunkID(item)
+		if !ok {
+			return nil, false
+		}
+		fields[i] = f
+	}
+	return fields, len(fields) != 0
+}
+
+// chunkInt returns the integer in the decoded record. JSON number is decoded as float64.
+func chunkInt(v interface{}) (int, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return int(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return int(rv.Float()), true
+	}
+	return 0, false
+}
+
+// chunkRecords splits the converted record into chunks.
+func (hook *FluentHook) chunkRecords(record interface{}) []interface{} {
+	m, ok := record.(map[string]interface{})
+	if hook.chunk == nil || !ok {
+		return []interface{}{record}
+	}
+
+	chunks := hook.chunk.split(m, hook.chunk.fields(hook.messageField))
+	records := make([]interface{}, len(chunks))
+	for i, c := range chunks {
+		records[i] = c
+	}
+	return records
+}
+
+// SetChunk sets settings for splitting a record with a large field into several records.
+// Chunking is disabled when conf is nil.
+func (hook *FluentHook) SetChunk(conf *ChunkConfig) {
+	hook.chunk = conf
+}
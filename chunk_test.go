@@ -0,0 +1,199 @@
+package logrus_fluent
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/fluent/fluent-logger-golang/fluent"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitValue(t *testing.T) {
+	a := assert.New(t)
+
+	a.Nil(splitValue("abcd", 4))
+	a.Nil(splitValue(1, 4))
+	a.Equal([]interface{}{"abcd", "efgh", "i"}, splitValue("abcdefghi", 4))
+	a.Equal([]interface{}{"あ", "い", "う"}, splitValue("あいう", 4), "not to break a multibyte character")
+	a.Equal([]interface{}{"あ", "い"}, splitValue("あい", 2), "a character larger than max")
+	a.Equal([]interface{}{[]byte("abc"), []byte("de")}, splitValue([]byte("abcde"), 3))
+}
+
+func TestChunkSplit(t *testing.T) {
+	a := assert.New(t)
+
+	conf := ChunkConfig{MaxSize: 4, Fields: []string{"sql", "body"}}
+	data := map[string]interface{}{
+		"sql":   "select * from t",
+		"body":  []byte("0123456"),
+		"user":  "alice",
+		"short": "abcdefgh", // not designated
+	}
+	chunks := conf.split(data, conf.Fields)
+	a.Len(chunks, 4)
+
+	id := chunks[0][ChunkIDField]
+	a.NotEmpty(id)
+	sqls := []string{"sele", "ct *", " fro", "m t"}
+	bodies := [][]byte{[]byte("0123"), []byte("456"), {}, {}}
+	for i, c := range chunks {
+		a.Equal(id, c[ChunkIDField])
+		a.Equal(i, c[ChunkIndexField])
+		a.Equal(4, c[ChunkTotalField])
+		a.Equal([]string{"sql", "body"}, c[ChunkFieldsField])
+		a.Equal(sqls[i], c["sql"])
+		a.Equal(bodies[i], c["body"])
+		a.Equal("alice", c["user"], "the other fields are copied into every chunk")
+		a.Equal("abcdefgh", c["short"])
+	}
+	a.Equal("select * from t", data["sql"], "the original data is not modified")
+
+	// not split
+	small := map[string]interface{}{"message": "abc"}
+	a.Equal([]map[string]interface{}{small}, ChunkConfig{MaxSize: 4}.split(small, []string{"message"}))
+	a.Equal([]map[string]interface{}{data}, ChunkConfig{}.split(data, conf.Fields))
+
+	// chunk id is unique
+	a.NotEqual(id, conf.split(data, conf.Fields)[0][ChunkIDField])
+}
+
+func TestChunkReassemble(t *testing.T) {
+	a := assert.New(t)
+
+	hook := NewHook(testHOST, -1)
+	hook.SetMessageField("msg")
+	hook.SetChunk(&ChunkConfig{MaxSize: 10})
+	hook.SetKeyOrder(KeyOrderSorted)
+
+	entry := logrus.NewEntry(logrus.New())
+	entry.Message = strings.Repeat("0123456789", 3) + "あいう"
+	entry.Level = logrus.InfoLevel
+	entry.Data = logrus.Fields{"tag": fieldTag, "user": "alice"}
+
+	_, data := hook.getTagAndData(entry)
+	records := hook.chunkRecords(hook.encodeData(data))
+	a.Len(records, 4)
+
+	// decode the records sent in msgpack and JSON.
+	var fromMsgpack, fromJSON []map[string]interface{}
+	for _, r := range records {
+		msg := fluent.Message{Tag: fieldTag, Time: 1, Record: hook.orderRecord(r)}
+		b, err := msg.MarshalMsg(nil)
+		a.NoError(err)
+		var decoded fluent.Message
+		_, err = decoded.UnmarshalMsg(b)
+		a.NoError(err)
+		fromMsgpack = append(fromMsgpack, decoded.Record.(map[string]interface{}))
+
+		b, err = json.Marshal(hook.orderRecord(r))
+		a.NoError(err)
+		var m map[string]interface{}
+		a.NoError(json.Unmarshal(b, &m))
+		fromJSON = append(fromJSON, m)
+	}
+
+	other := map[string]interface{}{"message": "other"}
+	for _, decoded := range [][]map[string]interface{}{fromMsgpack, fromJSON} {
+		// mixed with other records in reverse order.
+		input := []map[string]interface{}{other}
+		for i := len(decoded) - 1; i >= 0; i-- {
+			input = append(input, decoded[i])
+		}
+		result, err := ChunkConfig{}.Reassemble(input)
+		a.NoError(err)
+		a.Len(result, 2)
+		a.Equal(other, result[0])
+		a.Equal(entry.Message, result[1]["msg"], "the split fields are read from the chunks")
+		a.Equal("alice", result[1]["user"])
+		a.NotContains(result[1], ChunkIDField)
+		a.NotContains(result[1], ChunkIndexField)
+		a.NotContains(result[1], ChunkTotalField)
+		a.NotContains(result[1], ChunkFieldsField)
+	}
+
+	// incomplete chunks are returned as they are.
+	result, err := ChunkConfig{}.Reassemble(append([]map[string]interface{}{other}, fromMsgpack[1:]...))
+	a.True(errors.Is(err, ErrIncompleteChunks))
+	a.Equal(append([]map[string]interface{}{other}, fromMsgpack[1:]...), result)
+
+	// invalid chunks are returned as they are.
+	copyChunks := func(fn func(i int, c map[string]interface{})) []map[string]interface{} {
+		chunks := make([]map[string]interface{}, len(fromMsgpack))
+		for i, r := range fromMsgpack {
+			c := make(map[string]interface{}, len(r))
+			for k, v := range r {
+				c[k] = v
+			}
+			fn(i, c)
+			chunks[i] = c
+		}
+		return chunks
+	}
+	for _, invalid := range [][]map[string]interface{}{
+		copyChunks(func(i int, c map[string]interface{}) { delete(c, ChunkFieldsField) }),
+		copyChunks(func(i int, c map[string]interface{}) {
+			if i == 2 {
+				c["msg"] = 1
+			}
+		}),
+	} {
+		result, err = ChunkConfig{}.Reassemble(invalid)
+		a.True(errors.Is(err, ErrInvalidChunks), "%v", err)
+		a.Equal(invalid, result)
+	}
+}
+
+func TestSetChunk(t *testing.T) {
+	a := assert.New(t)
+
+	hook := NewHook(testHOST, -1)
+	hook.SetMessageField("msg")
+	data := map[string]interface{}{"msg": "0123456789"}
+	a.Equal([]interface{}{data}, hook.chunkRecords(data))
+
+	hook.SetChunk(&ChunkConfig{MaxSize: 5})
+	records := hook.chunkRecords(data)
+	a.Len(records, 2)
+	a.Equal("01234", records[0].(map[string]interface{})["msg"])
+	a.Equal([]interface{}{"str"}, hook.chunkRecords("str"))
+
+	// chunk fields come after the hook's own fields, and they are not dropped by the size limit.
+	a.Equal([]string{LevelField, "msg", "", ChunkIDField, ChunkIndexField, ChunkTotalField, ChunkFieldsField}, hook.ownFieldKeys())
+
+	hook, err := NewWithConfig(Config{DisableConnectionPool: true, Chunk: &ChunkConfig{MaxSize: 1}})
+	a.NoError(err)
+	a.Equal(1, hook.chunk.MaxSize)
+}
+
+func TestChunkSizeLimit(t *testing.T) {
+	a := assert.New(t)
+
+	hook := NewHook(testHOST, -1)
+	hook.SetChunk(&ChunkConfig{MaxSize: 10})
+	hook.SetSizeLimit(&SizeLimitConfig{MaxStringSize: 5})
+
+	entry := logrus.NewEntry(logrus.New())
+	entry.Message = strings.Repeat("0123456789", 3)
+	entry.Data = logrus.Fields{"tag": fieldTag, "user": "0123456789"}
+
+	_, data := hook.getTagAndData(entry)
+	var records []map[string]interface{}
+	for _, r := range hook.chunkRecords(hook.encodeData(data)) {
+		limited, err := hook.limitRecord(r)
+		a.NoError(err)
+		m := limited.(map[string]interface{})
+		a.Len(m[ChunkIDField], 32, "the chunk id is not truncated")
+		a.Len(m[MessageField], 10, "the split fields are not truncated")
+		a.Equal("01234...(truncated 5 bytes)", m["user"], "the other fields are truncated")
+		records = append(records, m)
+	}
+	a.Len(records, 3)
+
+	result, err := ChunkConfig{}.Reassemble(records)
+	a.NoError(err)
+	a.Len(result, 1)
+	a.Equal(entry.Message, result[0][MessageField])
+}
@@ -0,0 +1,350 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// basicTypes are checked for omitempty option without reflection.
+var basicTypes = map[string]struct{}{
+	"string": {}, "bool": {},
+	"int": {}, "int8": {}, "int16": {}, "int32": {}, "int64": {},
+	"uint": {}, "uint8": {}, "uint16": {}, "uint32": {}, "uint64": {},
+	"float32": {}, "float64": {},
+	"byte": {}, "rune": {},
+}
+
+// Generate returns the source code of MarshalFluent() methods for the struct types in the package directory.
+func Generate(dir string, typeNames, tagNames []string) ([]byte, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(pkgs) != 1 {
+		return nil, fmt.Errorf("%d packages found in %s", len(pkgs), dir)
+	}
+
+	var pkg *ast.Package
+	for _, p := range pkgs {
+		pkg = p
+	}
+	structs := findStructs(pkg)
+
+	g := &generator{structs: structs, tagNames: tagNames}
+	for _, name := range typeNames {
+		st, ok := structs[name]
+		if !ok {
+			return nil, fmt.Errorf("struct type %s is not found", name)
+		}
+		if err := g.generate(name, st); err != nil {
+			return nil, err
+		}
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by logrus-fluent-gen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&out, "package %s\n\n", pkg.Name)
+	if g.useReflect {
+		fmt.Fprintf(&out, "import \"reflect\"\n\n")
+	}
+	out.Write(g.buf.Bytes())
+	if g.useJSONEmpty {
+		out.WriteString(jsonEmptyFunc)
+	}
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("invalid generated code: %s\n%s", err.Error(), out.String())
+	}
+	return src, nil
+}
+
+// jsonEmptyFunc is emitted when the omitempty option of json tag needs reflection.
+const jsonEmptyFunc = `
+// fluentGenIsEmpty reports whether the value is empty in the semantics of encoding/json.
+func fluentGenIsEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.Interface, reflect.Ptr:
+		return v.IsZero()
+	}
+	return false
+}
+`
+
+// findStructs returns struct types declared in the package.
+func findStructs(pkg *ast.Package) map[string]*ast.StructType {
+	result := make(map[string]*ast.StructType)
+
+	// sort files to make the output stable.
+	names := make([]string, 0, len(pkg.Files))
+	for name := range pkg.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		ast.Inspect(pkg.Files[name], func(n ast.Node) bool {
+			ts, ok := n.(*ast.TypeSpec)
+			if !ok {
+				return true
+			}
+			if st, ok := ts.Type.(*ast.StructType); ok {
+				result[ts.Name.Name] = st
+			}
+			return false
+		})
+	}
+	return result
+}
+
+type generator struct {
+	buf          bytes.Buffer
+	structs      map[string]*ast.StructType
+	tagNames     []string
+	useReflect   bool
+	useJSONEmpty bool
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+// field is a parsed struct field.
+type field struct {
+	goName    string // selector from the receiver. (e.g. Embedded.Name)
+	name      string
+	typ       ast.Expr
+	omitEmpty bool
+	jsonEmpty bool
+}
+
+func (g *generator) generate(typeName string, st *ast.StructType) error {
+	g.printf("\n// MarshalFluent implements logrus_fluent.FluentMarshaler.\n")
+	g.printf("func (v %s) MarshalFluent() interface{} {\n", typeName)
+	g.printf("return v.FluentRecord()\n}\n\n")
+
+	g.printf("// FluentRecord returns fluentd record of %s.\n", typeName)
+	g.printf("// The values are not converted, and logrus_fluent.Converter converts them with its options.\n")
+	g.printf("func (v %s) FluentRecord() map[string]interface{} {\n", typeName)
+	g.printf("m := make(map[string]interface{}, %d)\n", g.countFields(st, map[string]bool{typeName: true}))
+	if err := g.generateFields(typeName, "", st, map[string]bool{typeName: true}); err != nil {
+		return err
+	}
+	g.printf("return m\n}\n")
+	return nil
+}
+
+// generateFields generates the code to set the fields of the struct.
+// The fields of embedded structs in the package are inlined.
+func (g *generator) generateFields(typeName, prefix string, st *ast.StructType, visiting map[string]bool) error {
+	for _, f := range st.Fields.List {
+		if len(f.Names) != 0 {
+			for _, ident := range f.Names {
+				if !ident.IsExported() {
+					continue
+				}
+				fd, ok, err := g.parseField(ident.Name, f)
+				if err != nil {
+					return fmt.Errorf("%s.%s: %s", typeName, ident.Name, err.Error())
+				}
+				if ok {
+					fd.goName = prefix + ident.Name
+					g.generateField(fd)
+				}
+			}
+			continue
+		}
+
+		// embedded field
+		name := embeddedName(f.Type)
+		if name == "" {
+			return fmt.Errorf("unsupported embedded field in %s", typeName)
+		}
+		v := "v." + prefix + name
+		_, isPtr := f.Type.(*ast.StarExpr)
+		ident, isLocal := embeddedType(f.Type).(*ast.Ident)
+		if isLocal && visiting[ident.Name] {
+			continue // skip embedded pointer to itself
+		}
+		if isLocal {
+			if est, ok := g.structs[ident.Name]; ok {
+				if isPtr {
+					g.printf("if %s != nil {\n", v)
+				}
+				visiting[ident.Name] = true
+				err := g.generateFields(typeName, prefix+name+".", est, visiting)
+				delete(visiting, ident.Name)
+				if err != nil {
+					return err
+				}
+				if isPtr {
+					g.printf("}\n")
+				}
+				continue
+			}
+		}
+		// the fields can't be inlined without converting the value.
+		return fmt.Errorf("%s: embedded field %s declared in another package is not supported", typeName, name)
+	}
+	return nil
+}
+
+// countFields returns the number of fields to allocate the record.
+func (g *generator) countFields(st *ast.StructType, visiting map[string]bool) int {
+	n := 0
+	for _, f := range st.Fields.List {
+		if len(f.Names) != 0 {
+			n += len(f.Names)
+			continue
+		}
+		if ident, ok := embeddedType(f.Type).(*ast.Ident); ok && !visiting[ident.Name] {
+			if est, ok := g.structs[ident.Name]; ok {
+				visiting[ident.Name] = true
+				n += g.countFields(est, visiting)
+				delete(visiting, ident.Name)
+			}
+		}
+	}
+	return n
+}
+
+// embeddedType returns the type of the embedded field without pointer.
+func embeddedType(expr ast.Expr) ast.Expr {
+	if t, ok := expr.(*ast.StarExpr); ok {
+		return t.X
+	}
+	return expr
+}
+
+// embeddedName returns the field name of the embedded type.
+func embeddedName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.StarExpr:
+		return embeddedName(t.X)
+	case *ast.SelectorExpr:
+		return t.Sel.Name
+	}
+	return ""
+}
+
+// parseField parses the struct tag like logrus_fluent.Converter.
+func (g *generator) parseField(goName string, f *ast.Field) (field, bool, error) {
+	fd := field{goName: goName, name: goName, typ: f.Type}
+	if f.Tag == nil {
+		return fd, true, nil
+	}
+
+	tags, err := strconv.Unquote(f.Tag.Value)
+	if err != nil {
+		return fd, false, err
+	}
+	for _, tagName := range g.tagNames {
+		value, ok := reflect.StructTag(tags).Lookup(tagName)
+		if !ok {
+			continue
+		}
+
+		parts := strings.Split(value, ",")
+		tag, opts := parts[0], parts[1:]
+		isJSON := tagName == "json"
+		switch {
+		case isJSON && value == "-":
+			return fd, false, nil
+		case !isJSON && tag == "-":
+			return fd, false, nil
+		}
+
+		if tag != "" {
+			fd.name = tag
+		}
+		for _, opt := range opts {
+			switch opt {
+			case "omitempty":
+				fd.omitEmpty = true
+				fd.jsonEmpty = isJSON
+			case "string":
+				if isJSON {
+					return fd, false, fmt.Errorf("`string` option is not supported")
+				}
+			}
+		}
+		return fd, true, nil
+	}
+	return fd, true, nil
+}
+
+func (g *generator) generateField(f field) {
+	cond := g.nonEmptyCondition(f)
+	if cond != "" {
+		g.printf("if %s {\n", cond)
+	}
+
+	// the values are converted by the converter which calls MarshalFluent().
+	g.printf("m[%q] = v.%s\n", f.name, f.goName)
+	if cond != "" {
+		g.printf("}\n")
+	}
+}
+
+// isBasicType checks the type is a predeclared basic type.
+func isBasicType(expr ast.Expr) bool {
+	ident, ok := expr.(*ast.Ident)
+	if !ok {
+		return false
+	}
+	_, ok = basicTypes[ident.Name]
+	return ok
+}
+
+// nonEmptyCondition returns the condition to check the field is not empty for omitempty option.
+func (g *generator) nonEmptyCondition(f field) string {
+	if !f.omitEmpty {
+		return ""
+	}
+
+	v := "v." + f.goName
+	if isBasicType(f.typ) {
+		switch f.typ.(*ast.Ident).Name {
+		case "string":
+			return v + ` != ""`
+		case "bool":
+			return v
+		default:
+			return v + " != 0"
+		}
+	}
+
+	if f.jsonEmpty {
+		switch f.typ.(type) {
+		case *ast.ArrayType, *ast.MapType:
+			return "len(" + v + ") != 0"
+		case *ast.StarExpr, *ast.InterfaceType:
+			return v + " != nil"
+		}
+		g.useReflect = true
+		g.useJSONEmpty = true
+		return "!fluentGenIsEmpty(reflect.ValueOf(" + v + "))"
+	}
+	g.useReflect = true
+	return "!reflect.ValueOf(" + v + ").IsZero()"
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestGenerateGolden checks the generated code of the example package is up to date.
+// The equivalence with the reflective converter is tested in the example package.
+func TestGenerateGolden(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := filepath.Join("internal", "example")
+	src, err := Generate(dir, []string{"Request", "User", "Node"}, []string{"fluent", "json"})
+	assert.NoError(err)
+
+	golden, err := os.ReadFile(filepath.Join(dir, "request_fluent.go"))
+	assert.NoError(err)
+	assert.Equal(string(golden), string(src))
+}
+
+func TestGenerateError(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "a.go"), []byte(`package a
+
+type A struct {
+	ID int `+"`json:\"id,string\"`"+`
+}
+`), 0o644)
+	assert.NoError(err)
+
+	_, err = Generate(dir, []string{"B"}, []string{"json"})
+	assert.EqualError(err, "struct type B is not found")
+
+	_, err = Generate(dir, []string{"A"}, []string{"json"})
+	assert.EqualError(err, "A.ID: `string` option is not supported")
+
+	src, err := Generate(dir, []string{"A"}, []string{"fluent"})
+	assert.NoError(err)
+	assert.Contains(string(src), `m["ID"] = v.ID`)
+	assert.NotContains(string(src), `"reflect"`)
+	assert.NotContains(string(src), "import", "the generated code doesn't depend on the converter")
+
+	err = os.WriteFile(filepath.Join(dir, "c.go"), []byte(`package a
+
+import "time"
+
+type C struct {
+	time.Time
+}
+`), 0o644)
+	assert.NoError(err)
+	_, err = Generate(dir, []string{"C"}, []string{"json"})
+	assert.EqualError(err, "C: embedded field Time declared in another package is not supported")
+}
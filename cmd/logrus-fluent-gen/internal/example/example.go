@@ -0,0 +1,66 @@
+// Package example has struct types to test the generated code of logrus-fluent-gen.
+package example
+
+import "time"
+
+//go:generate go run ../.. -type=Request,User,Node -tags=fluent,json
+
+// Request is an example of a large struct logged at high QPS.
+type Request struct {
+	ID         int64             `fluent:"id"`
+	Method     string            `fluent:"method"`
+	Path       string            `json:"path"`
+	Referer    string            `json:"referer,omitempty"`
+	Latency    float64           `fluent:"latency,omitempty"`
+	Cached     bool              `json:"cached,omitempty"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Tags       []string          `fluent:"tags"`
+	Scores     map[string]int    `json:"scores"`
+	Users      []User            `json:"users,omitempty"`
+	Hash       [4]byte           `json:"hash"`
+	Body       []byte            `json:"body,omitempty"`
+	User       *User             `json:"user,omitempty"`
+	Start      time.Time         `json:"start"`
+	Status     Status            `json:"status,omitempty"`
+	Client     Client            `json:"client"`
+	Elapsed    time.Duration     `json:"elapsed"`
+	Internal   string            `fluent:"-"`
+	Ignored    string            `json:"-"`
+	NoTag      string
+	unexported string
+
+	Trace
+	*Route
+}
+
+// User is an example of a nested struct.
+type User struct {
+	ID    int    `fluent:"user_id"`
+	Name  string `fluent:"name" json:"user_name"`
+	Email string `fluent:"email,omitempty"`
+}
+
+// Trace is an example of an embedded struct.
+type Trace struct {
+	TraceID string `json:"trace_id"`
+	SpanID  string `json:"span_id,omitempty"`
+}
+
+// Route is an example of an embedded pointer.
+type Route struct {
+	Name string `json:"route"`
+}
+
+// Client is an example of a nested struct without the generated method.
+type Client struct {
+	IP string `fluent:"ip" json:"client_ip"`
+}
+
+// Status is an example of a named basic type.
+type Status int
+
+// Node is an example of a self-referential struct.
+type Node struct {
+	Name string `fluent:"name"`
+	Next *Node  `fluent:"next"`
+}
@@ -0,0 +1,153 @@
+package example
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tinylib/msgp/msgp"
+
+	logrus_fluent "github.com/evalphobia/logrus_fluent"
+)
+
+// plain types have the same fields without the generated methods.
+type (
+	plainRequest Request
+	plainUser    User
+)
+
+// plainNode is Node without the generated methods.
+type plainNode struct {
+	Name string     `fluent:"name"`
+	Next *plainNode `fluent:"next"`
+}
+
+var reflectConverter = logrus_fluent.Converter{TagNames: []string{"fluent", "json"}}
+
+// converters have the same tag names as the generated code.
+var converters = []logrus_fluent.Converter{
+	reflectConverter,
+	{
+		TagNames:         []string{"fluent", "json"},
+		TimeEncoding:     logrus_fluent.TimeUnix,
+		DurationEncoding: logrus_fluent.DurationString,
+		BytesEncoding:    logrus_fluent.BytesHex,
+		KeyOrder:         logrus_fluent.KeyOrderSorted,
+		MaxDepth:         2,
+	},
+}
+
+func TestFluentRecordEquivalence(t *testing.T) {
+	assert := assert.New(t)
+
+	user := &User{ID: 1, Name: "alice"}
+	tests := []Request{
+		{},
+		{
+			ID:         100,
+			Method:     "GET",
+			Path:       "/users/1",
+			Referer:    "https://example.com/",
+			Latency:    0.25,
+			Cached:     true,
+			Headers:    map[string]string{"Accept": "*/*"},
+			Tags:       []string{"a", "b"},
+			User:       user,
+			Start:      time.Date(2026, 1, 2, 3, 4, 5, 6, time.UTC),
+			Status:     2,
+			Client:     Client{IP: "127.0.0.1"},
+			Elapsed:    time.Second,
+			Internal:   "internal",
+			Ignored:    "ignored",
+			NoTag:      "no tag",
+			unexported: "unexported",
+			Scores:     map[string]int{"math": 90},
+			Hash:       [4]byte{1, 2, 3, 4},
+			Body:       []byte("body"),
+			Users:      []User{{ID: 2, Email: "bob@example.com"}},
+			Trace:      Trace{TraceID: "trace", SpanID: "span"},
+			Route:      &Route{Name: "user"},
+		},
+		{
+			Tags:   []string{},
+			Scores: map[string]int{},
+		},
+	}
+
+	for _, c := range converters {
+		for _, tt := range tests {
+			expected := c.ConvertToValue(plainRequest(tt))
+			assert.Equal(expected, c.ConvertToValue(tt))
+			assert.Equal(expected, c.ConvertToValue(&tt))
+			assertMsgpack(t, c, expected, tt)
+		}
+		assert.Equal(c.ConvertToValue(plainUser(*user)), c.ConvertToValue(user))
+	}
+	assert.Equal(reflectConverter.ConvertToValue(plainRequest(tests[1])), logrus_fluent.ConvertToValue(tests[1], logrus_fluent.TagName))
+
+	// the nested values are converted with the tag names of the converter.
+	record := logrus_fluent.Converter{TagNames: []string{"json"}}.ConvertToValue(tests[1]).(map[string]interface{})
+	assert.Equal(map[string]interface{}{"client_ip": "127.0.0.1"}, record["client"])
+}
+
+func TestFluentRecordCycle(t *testing.T) {
+	assert := assert.New(t)
+
+	n := &Node{Name: "a"}
+	n.Next = n
+	plain := &plainNode{Name: "a"}
+	plain.Next = plain
+
+	deep := &Node{Name: "1", Next: &Node{Name: "2", Next: &Node{Name: "3"}}}
+	plainDeep := &plainNode{Name: "1", Next: &plainNode{Name: "2", Next: &plainNode{Name: "3"}}}
+
+	for _, c := range converters {
+		expected := c.ConvertToValue(plain)
+		assert.Equal(map[string]interface{}{"name": "a", "next": logrus_fluent.CycleMarker}, expected)
+		assert.Equal(expected, c.ConvertToValue(n))
+		assertMsgpack(t, c, expected, n)
+
+		expected = c.ConvertToValue(plainDeep)
+		assert.Equal(expected, c.ConvertToValue(deep))
+		assertMsgpack(t, c, expected, deep)
+	}
+}
+
+// assertMsgpack checks the value is encoded in the same way as the expected value.
+func assertMsgpack(t *testing.T, c logrus_fluent.Converter, expected, v interface{}) {
+	b, err := c.AppendMsgpack(nil, v)
+	assert.NoError(t, err)
+	actual, _, err := msgp.ReadIntfBytes(b)
+	assert.NoError(t, err)
+
+	b, err = c.AppendMsgpack(nil, expected)
+	assert.NoError(t, err)
+	want, _, err := msgp.ReadIntfBytes(b)
+	assert.NoError(t, err)
+	assert.Equal(t, want, actual)
+}
+
+func BenchmarkFluentRecord(b *testing.B) {
+	r := Request{
+		ID:      100,
+		Method:  "GET",
+		Path:    "/users/1",
+		Referer: "https://example.com/",
+		Latency: 0.25,
+		Headers: map[string]string{"Accept": "*/*", "User-Agent": "bench"},
+		Tags:    []string{"a", "b"},
+		Trace:   Trace{TraceID: "trace", SpanID: "span"},
+	}
+	b.Run("generated", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			reflectConverter.ConvertToValue(r)
+		}
+	})
+	b.Run("reflection", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			reflectConverter.ConvertToValue(plainRequest(r))
+		}
+	})
+}
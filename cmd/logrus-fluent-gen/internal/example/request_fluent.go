@@ -0,0 +1,104 @@
+// Code generated by logrus-fluent-gen. DO NOT EDIT.
+
+package example
+
+import "reflect"
+
+// MarshalFluent implements logrus_fluent.FluentMarshaler.
+func (v Request) MarshalFluent() interface{} {
+	return v.FluentRecord()
+}
+
+// FluentRecord returns fluentd record of Request.
+// The values are not converted, and logrus_fluent.Converter converts them with its options.
+func (v Request) FluentRecord() map[string]interface{} {
+	m := make(map[string]interface{}, 24)
+	m["id"] = v.ID
+	m["method"] = v.Method
+	m["path"] = v.Path
+	if v.Referer != "" {
+		m["referer"] = v.Referer
+	}
+	if v.Latency != 0 {
+		m["latency"] = v.Latency
+	}
+	if v.Cached {
+		m["cached"] = v.Cached
+	}
+	if len(v.Headers) != 0 {
+		m["headers"] = v.Headers
+	}
+	m["tags"] = v.Tags
+	m["scores"] = v.Scores
+	if len(v.Users) != 0 {
+		m["users"] = v.Users
+	}
+	m["hash"] = v.Hash
+	if len(v.Body) != 0 {
+		m["body"] = v.Body
+	}
+	if v.User != nil {
+		m["user"] = v.User
+	}
+	m["start"] = v.Start
+	if !fluentGenIsEmpty(reflect.ValueOf(v.Status)) {
+		m["status"] = v.Status
+	}
+	m["client"] = v.Client
+	m["elapsed"] = v.Elapsed
+	m["NoTag"] = v.NoTag
+	m["trace_id"] = v.Trace.TraceID
+	if v.Trace.SpanID != "" {
+		m["span_id"] = v.Trace.SpanID
+	}
+	if v.Route != nil {
+		m["route"] = v.Route.Name
+	}
+	return m
+}
+
+// MarshalFluent implements logrus_fluent.FluentMarshaler.
+func (v User) MarshalFluent() interface{} {
+	return v.FluentRecord()
+}
+
+// FluentRecord returns fluentd record of User.
+// The values are not converted, and logrus_fluent.Converter converts them with its options.
+func (v User) FluentRecord() map[string]interface{} {
+	m := make(map[string]interface{}, 3)
+	m["user_id"] = v.ID
+	m["name"] = v.Name
+	if v.Email != "" {
+		m["email"] = v.Email
+	}
+	return m
+}
+
+// MarshalFluent implements logrus_fluent.FluentMarshaler.
+func (v Node) MarshalFluent() interface{} {
+	return v.FluentRecord()
+}
+
+// FluentRecord returns fluentd record of Node.
+// The values are not converted, and logrus_fluent.Converter converts them with its options.
+func (v Node) FluentRecord() map[string]interface{} {
+	m := make(map[string]interface{}, 2)
+	m["name"] = v.Name
+	m["next"] = v.Next
+	return m
+}
+
+// fluentGenIsEmpty reports whether the value is empty in the semantics of encoding/json.
+func fluentGenIsEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.Interface, reflect.Ptr:
+		return v.IsZero()
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+// Command logrus-fluent-gen generates MarshalFluent() method for struct types
+// to convert them to fluentd records without reflection of the struct fields.
+//
+//	//go:generate logrus-fluent-gen -type=Request,User
+//
+// The generated method implements logrus_fluent.FluentMarshaler.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	typeNames := flag.String("type", "", "comma separated list of struct type names; required")
+	tagNames := flag.String("tags", "fluent", "comma separated list of struct tag names in priority order")
+	output := flag.String("output", "", "output file name; default <dir>/<first type>_fluent.go")
+	flag.Parse()
+
+	if *typeNames == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	dir := "."
+	if args := flag.Args(); len(args) != 0 {
+		dir = args[0]
+	}
+
+	types := strings.Split(*typeNames, ",")
+	src, err := Generate(dir, types, strings.Split(*tagNames, ","))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "logrus-fluent-gen: %s\n", err.Error())
+		os.Exit(1)
+	}
+
+	name := *output
+	if name == "" {
+		name = filepath.Join(dir, strings.ToLower(types[0])+"_fluent.go")
+	}
+	if err := os.WriteFile(name, src, 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "logrus-fluent-gen: %s\n", err.Error())
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,73 @@
+package logrus_fluent
+
+import "github.com/sirupsen/logrus"
+
+// defaultCollisionPrefix is used for CollisionPrefix when prefix is empty.
+const defaultCollisionPrefix = "fields."
+
+// CollisionPolicy decides which value is used when a log field has
+// the same name as the field set by the hook. (e.g. level, message)
+type CollisionPolicy int
+
+const (
+	// CollisionDefault overwrites level and time fields by the hook,
+	// and keeps message field from the log fields.
+	CollisionDefault CollisionPolicy = iota
+	// CollisionOverwrite overwrites the log field by the hook.
+	CollisionOverwrite
+	// CollisionKeep keeps the log field.
+	CollisionKeep
+	// CollisionPrefix adds prefix to the name of the log field.
+	CollisionPrefix
+)
+
+// SetFieldsKey sets the key to nest all of the log fields under.
+// The fields set by the hook stay at the top level.
+func (hook *FluentHook) SetFieldsKey(key string) {
+	hook.fieldsKey = key
+}
+
+// SetCollisionPolicy sets the policy for the field name collision.
+func (hook *FluentHook) SetCollisionPolicy(policy CollisionPolicy) {
+	hook.collisionPolicy = policy
+}
+
+// SetCollisionPrefix sets the prefix used by CollisionPrefix policy.
+func (hook *FluentHook) SetCollisionPrefix(prefix string) {
+	hook.collisionPrefix = prefix
+}
+
+// mergeFields merges the log fields into the fields set by the hook.
+func (hook *FluentHook) mergeFields(data, fields logrus.Fields) logrus.Fields {
+	if hook.fieldsKey != "" {
+		if len(fields) != 0 {
+			data[hook.fieldsKey] = fields
+		}
+		return data
+	}
+
+	for k, v := range fields {
+		if _, ok := data[k]; !ok {
+			data[k] = v
+			continue
+		}
+
+		switch hook.collisionPolicy {
+		case CollisionOverwrite:
+			continue
+		case CollisionKeep:
+			data[k] = v
+		case CollisionPrefix:
+			prefix := hook.collisionPrefix
+			if prefix == "" {
+				prefix = defaultCollisionPrefix
+			}
+			data[prefix+k] = v
+		default:
+			if k == hook.messageField {
+				data[k] = v
+			}
+		}
+	}
+	return data
+}
@@ -0,0 +1,86 @@
+package logrus_fluent
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetFieldsKey(t *testing.T) {
+	a := assert.New(t)
+
+	hook := NewHook(testHOST, -1)
+	hook.SetFieldsKey("fields")
+
+	entry := logrus.NewEntry(logrus.New())
+	entry.Data = logrus.Fields{
+		"tag":     fieldTag,
+		"level":   "user level",
+		"message": fieldMessage,
+		"value":   fieldValue,
+	}
+	entry.Level = logrus.ErrorLevel
+	entry.Message = entryMessage
+
+	tag, data := hook.getTagAndData(entry)
+	a.Equal(fieldTag, tag)
+	a.Equal("error", data["level"])
+	a.Equal(entryMessage, data["message"])
+	a.Len(data, 3)
+
+	fields, ok := data["fields"].(logrus.Fields)
+	a.True(ok)
+	a.Equal("user level", fields["level"])
+	a.Equal(fieldMessage, fields["message"])
+	a.Equal(fieldValue, fields["value"])
+	a.NotContains(fields, "tag")
+}
+
+func TestSetCollisionPolicy(t *testing.T) {
+	a := assert.New(t)
+
+	tests := []struct {
+		policy  CollisionPolicy
+		prefix  string
+		level   interface{}
+		message interface{}
+		extra   map[string]interface{}
+	}{
+		{CollisionDefault, "", "error", fieldMessage, nil},
+		{CollisionOverwrite, "", "error", entryMessage, nil},
+		{CollisionKeep, "", "user level", fieldMessage, nil},
+		{CollisionPrefix, "", "error", entryMessage, map[string]interface{}{
+			"fields.level":   "user level",
+			"fields.message": fieldMessage,
+		}},
+		{CollisionPrefix, "_", "error", entryMessage, map[string]interface{}{
+			"_level":   "user level",
+			"_message": fieldMessage,
+		}},
+	}
+
+	for _, tt := range tests {
+		hook := NewHook(testHOST, -1)
+		hook.SetTag(staticTag)
+		hook.SetCollisionPolicy(tt.policy)
+		hook.SetCollisionPrefix(tt.prefix)
+
+		entry := logrus.NewEntry(logrus.New())
+		entry.Data = logrus.Fields{
+			"level":   "user level",
+			"message": fieldMessage,
+			"value":   fieldValue,
+		}
+		entry.Level = logrus.ErrorLevel
+		entry.Message = entryMessage
+
+		_, data := hook.getTagAndData(entry)
+		a.Equal(tt.level, data["level"], "policy=%d", tt.policy)
+		a.Equal(tt.message, data["message"], "policy=%d", tt.policy)
+		a.Equal(fieldValue, data["value"], "policy=%d", tt.policy)
+		for k, v := range tt.extra {
+			a.Equal(v, data[k], "policy=%d", tt.policy)
+		}
+	}
+}
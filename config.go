@@ -15,8 +15,42 @@ type Config struct {
 	DisableConnectionPool bool // Fluent client will be created every logging if true.
 	DefaultTag            string
 	DefaultMessageField   string
+	DefaultLevelField     string
+	DefaultLevelEncoder   LevelEncoder
+	DisableLevelField     bool // level field is not added into the fields if true.
+	DefaultTimeField      string
 	DefaultIgnoreFields   map[string]struct{}
+	DefaultRenameFields   map[string]string
 	DefaultFilters        map[string]func(interface{}) interface{}
+	DefaultFields         logrus.Fields // added into every log. fields in the log entry have priority.
+	DefaultFieldProviders map[string]FieldProvider
+	KeepIgnoredDefaults   bool // default fields are kept even if the field name is ignored.
+	ContextExtractors     []ContextExtractor
+	DefaultFieldPreset    *FieldPreset // field names are overwritten by DefaultXxxField if set.
+	DefaultFieldsKey      string       // log fields are nested under this key if set.
+	CollisionPolicy       CollisionPolicy
+	CollisionPrefix       string
+	Flatten               *FlattenConfig   // nested fields are flattened into dotted keys if set.
+	OmitEmpty             *OmitEmptyConfig // empty values are dropped from the record if set.
+	SizeLimit             *SizeLimitConfig // strings, collections and the record are truncated if set.
+	Chunk                 *ChunkConfig     // a record with a large field is split into several records if set.
+	Metadata              MetadataConfig
+	Kubernetes            *KubernetesConfig // kubernetes metadata is added if set.
+	Caller                *CallerConfig     // caller information is added if set.
+	Trace                 *TraceConfig      // OpenTelemetry trace id and span id are added if set.
+	ErrorEncoder          ErrorEncoder      // error in a struct or a map is converted by this function if set.
+	SwallowPanic          bool              // RecoverAndLog doesn't raise the panic again if true.
+	Marshalers            []Marshaler       // checked before reflection in priority order. DefaultMarshalers is used if nil.
+	TagNames              []string          // struct tag names in priority order. default is ["fluent"].
+	TimeEncoding          TimeEncoding      // encoding type for time.Time. default is TimeString.
+	TimeLayout            string            // layout for TimeString. default is RFC3339Nano.
+	TimeLocation          *time.Location    // location for TimeString.
+	DurationEncoding      DurationEncoding  // encoding type for time.Duration. default is DurationNanoseconds.
+	BytesEncoding         BytesEncoding     // encoding type for byte slices and byte arrays. default is BytesBinary.
+	MaxDepth              int               // max depth of nested values. default is 32.
+	MaxCollectionSize     int               // max number of items in a nested map or a slice. unlimited if 0.
+	DirectEncoding        bool              // records are encoded into msgpack without intermediate maps if true.
+	KeyOrder              KeyOrder          // order of keys in the encoded record. default is KeyOrderNone.
 
 	// from fluent.Config
 	// see https://github.com/fluent/fluent-logger-golang/blob/master/fluent/fluent.go
@@ -0,0 +1,32 @@
+package logrus_fluent
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ContextExtractor returns log fields from entry.Context.
+type ContextExtractor func(ctx context.Context) logrus.Fields
+
+// AddContextExtractor adds a function to extract log fields from entry.Context.
+// The fields in the log entry have priority over the extracted fields.
+func (hook *FluentHook) AddContextExtractor(fn ContextExtractor) {
+	hook.contextExtractors = append(hook.contextExtractors, fn)
+}
+
+// addContextFields adds the fields extracted from entry.Context.
+func (hook *FluentHook) addContextFields(entry *logrus.Entry, fields logrus.Fields) {
+	if entry.Context == nil {
+		return
+	}
+
+	for _, fn := range hook.contextExtractors {
+		for k, v := range fn(entry.Context) {
+			if _, ok := hook.ignoreFields[k]; ok {
+				continue
+			}
+			hook.addField(fields, k, v)
+		}
+	}
+}
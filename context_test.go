@@ -0,0 +1,50 @@
+package logrus_fluent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type contextKey string
+
+func TestAddContextExtractor(t *testing.T) {
+	a := assert.New(t)
+
+	hook := NewHook(testHOST, -1)
+	hook.AddDefaultField("request_id", "default")
+	hook.AddDefaultField("tenant_id", "default")
+	hook.AddContextExtractor(func(ctx context.Context) logrus.Fields {
+		return logrus.Fields{
+			"request_id": ctx.Value(contextKey("request_id")),
+			"tenant_id":  ctx.Value(contextKey("tenant_id")),
+			"user_id":    ctx.Value(contextKey("user_id")),
+			"secret":     "should be ignored",
+		}
+	})
+	hook.AddIgnore("secret")
+
+	ctx := context.Background()
+	ctx = context.WithValue(ctx, contextKey("request_id"), "req-1")
+	ctx = context.WithValue(ctx, contextKey("tenant_id"), "tenant-1")
+	ctx = context.WithValue(ctx, contextKey("user_id"), 100)
+
+	entry := logrus.NewEntry(logrus.New()).WithContext(ctx).WithFields(logrus.Fields{
+		"tag":     fieldTag,
+		"user_id": 200,
+	})
+
+	_, data := hook.getTagAndData(entry)
+	a.Equal("req-1", data["request_id"], "context field should have priority over default field")
+	a.Equal("tenant-1", data["tenant_id"])
+	a.Equal(200, data["user_id"], "entry field should have priority over context field")
+	a.NotContains(data, "secret")
+
+	// without context
+	entry = logrus.NewEntry(logrus.New()).WithField("tag", fieldTag)
+	_, data = hook.getTagAndData(entry)
+	a.Equal("default", data["request_id"])
+	a.NotContains(data, "user_id")
+}
@@ -0,0 +1,55 @@
+package logrus_fluent
+
+import (
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// FieldProvider returns the value of default field for the log entry.
+type FieldProvider func(entry *logrus.Entry) interface{}
+
+// AddDefaultField adds a static field added into every log.
+// The fields in the log entry have priority over the default fields.
+func (hook *FluentHook) AddDefaultField(name string, value interface{}) {
+	hook.AddFieldProvider(name, func(*logrus.Entry) interface{} {
+		return value
+	})
+}
+
+// AddFieldProvider adds a default field whose value is evaluated on every log.
+func (hook *FluentHook) AddFieldProvider(name string, fn FieldProvider) {
+	hook.defaultFields[name] = fn
+}
+
+// AddCachedFieldProvider adds a default field whose value is evaluated only once on the first log.
+func (hook *FluentHook) AddCachedFieldProvider(name string, fn func() interface{}) {
+	var once sync.Once
+	var value interface{}
+	hook.AddFieldProvider(name, func(*logrus.Entry) interface{} {
+		once.Do(func() {
+			value = fn()
+		})
+		return value
+	})
+}
+
+// SetKeepIgnoredDefaultFields sets whether the default fields are kept
+// even if the field name is added by AddIgnore.
+func (hook *FluentHook) SetKeepIgnoredDefaultFields(keep bool) {
+	hook.keepIgnoredDefaultFields = keep
+}
+
+// addDefaultFields adds the default fields which don't exist in the log entry.
+func (hook *FluentHook) addDefaultFields(entry *logrus.Entry, fields logrus.Fields) {
+	for k, fn := range hook.defaultFields {
+		_, ignored := hook.ignoreFields[k]
+		if ignored && !hook.keepIgnoredDefaultFields {
+			continue
+		}
+		if _, ok := entry.Data[k]; ok && !ignored {
+			continue
+		}
+		hook.addField(fields, k, fn(entry))
+	}
+}
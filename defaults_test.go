@@ -0,0 +1,75 @@
+package logrus_fluent
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultFields(t *testing.T) {
+	a := assert.New(t)
+
+	var calls, cachedCalls int
+	hook, err := NewWithConfig(Config{
+		DisableConnectionPool: true,
+		DefaultMessageField:   MessageField,
+		DefaultFields: logrus.Fields{
+			"service": "api",
+			"env":     "production",
+		},
+		DefaultFieldProviders: map[string]FieldProvider{
+			"msg_len": func(entry *logrus.Entry) interface{} {
+				calls++
+				return len(entry.Message)
+			},
+		},
+	})
+	a.NoError(err)
+	hook.AddCachedFieldProvider("hostname", func() interface{} {
+		cachedCalls++
+		return "host-1"
+	})
+	hook.AddFilter("service", func(v interface{}) interface{} {
+		return "svc-" + v.(string)
+	})
+
+	entry := logrus.NewEntry(logrus.New())
+	entry.Data = logrus.Fields{
+		"tag": fieldTag,
+		"env": "staging",
+	}
+	entry.Message = entryMessage
+
+	for i := 0; i < 3; i++ {
+		_, data := hook.getTagAndData(entry)
+		a.Equal("svc-api", data["service"])
+		a.Equal("staging", data["env"], "entry field should have priority over default field")
+		a.Equal(len(entryMessage), data["msg_len"])
+		a.Equal("host-1", data["hostname"])
+	}
+	a.Equal(3, calls)
+	a.Equal(1, cachedCalls)
+}
+
+func TestDefaultFieldsWithIgnore(t *testing.T) {
+	a := assert.New(t)
+
+	hook := NewHook(testHOST, -1)
+	hook.AddDefaultField("service", "api")
+	hook.AddIgnore("service")
+
+	entry := logrus.NewEntry(logrus.New())
+	entry.Data = logrus.Fields{"tag": fieldTag}
+
+	_, data := hook.getTagAndData(entry)
+	a.NotContains(data, "service")
+
+	hook.SetKeepIgnoredDefaultFields(true)
+	_, data = hook.getTagAndData(entry)
+	a.Equal("api", data["service"])
+
+	entry.Data["service"] = "user value"
+	_, data = hook.getTagAndData(entry)
+	a.Equal("api", data["service"], "ignored entry field should not overwrite default field")
+}
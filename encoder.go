@@ -0,0 +1,201 @@
+package logrus_fluent
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// structEncoders caches structEncoder for each struct type and tag names.
+var structEncoders sync.Map // map[structEncoderKey]*structEncoder
+
+type structEncoderKey struct {
+	typ      reflect.Type
+	tagNames string
+}
+
+// structEncoder has precomputed fields of a struct type.
+type structEncoder struct {
+	fields []structField
+	flat   bool // no embedded struct and no duplicated name. the fields can be encoded in order without a map.
+
+	sortedFields []structField // fields sorted by name for KeyOrderSorted. only for flat struct.
+}
+
+type structField struct {
+	index     int
+	name      string
+	omitEmpty bool
+	jsonEmpty bool // omitempty uses the semantics of encoding/json.
+	asString  bool // `string` option of encoding/json.
+	embedded  bool // anonymous struct or pointer to struct. the fields are merged into the parent.
+	basic     bool // basic kind without any method. the value is encoded without conversion.
+}
+
+// getStructEncoder returns cached structEncoder, or compiles it for the first time.
+func getStructEncoder(t reflect.Type, tagNames string) *structEncoder {
+	key := structEncoderKey{typ: t, tagNames: tagNames}
+	if enc, ok := structEncoders.Load(key); ok {
+		return enc.(*structEncoder)
+	}
+
+	enc, _ := structEncoders.LoadOrStore(key, compileStructEncoder(t, strings.Split(tagNames, ",")))
+	return enc.(*structEncoder)
+}
+
+// compileStructEncoder parses the fields and the tags of the struct type.
+func compileStructEncoder(t reflect.Type, tagNames []string) *structEncoder {
+	enc := &structEncoder{}
+	for i, max := 0, t.NumField(); i < max; i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+
+		if f.Anonymous {
+			tt := f.Type
+			if tt.Kind() == reflect.Ptr {
+				tt = tt.Elem()
+			}
+			if tt.Kind() == reflect.Struct {
+				enc.fields = append(enc.fields, structField{index: i, embedded: true})
+			}
+			continue
+		}
+
+		field, ok := parseField(f, tagNames)
+		if !ok {
+			continue
+		}
+		field.index = i
+		field.basic = isBasicType(f.Type)
+		enc.fields = append(enc.fields, field)
+	}
+
+	enc.flat = true
+	names := make(map[string]struct{}, len(enc.fields))
+	for _, f := range enc.fields {
+		if _, ok := names[f.name]; ok || f.embedded {
+			enc.flat = false
+			break
+		}
+		names[f.name] = struct{}{}
+	}
+	if enc.flat {
+		enc.sortedFields = append([]structField(nil), enc.fields...)
+		sort.Slice(enc.sortedFields, func(i, j int) bool {
+			return enc.sortedFields[i].name < enc.sortedFields[j].name
+		})
+	}
+	return enc
+}
+
+// isBasicType checks the type is bool, number or string kind without any method.
+func isBasicType(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.String:
+		return reflect.PtrTo(t).NumMethod() == 0
+	}
+	return false
+}
+
+// parseField parses the first found tag in the tag names.
+// It returns false when the field is omitted by `-` tag.
+func parseField(f reflect.StructField, tagNames []string) (structField, bool) {
+	field := structField{name: f.Name}
+	for _, tagName := range tagNames {
+		value, ok := f.Tag.Lookup(tagName)
+		if !ok {
+			continue
+		}
+
+		tag, opts := splitTags(value)
+		isJSON := tagName == "json"
+		switch {
+		case isJSON && value == "-":
+			return field, false // `json:"-,"` means the field name is "-".
+		case !isJSON && tag == "-":
+			return field, false // skip `-` tag
+		}
+
+		if tag != "" {
+			field.name = tag
+		}
+		field.omitEmpty = opts.Has("omitempty")
+		field.jsonEmpty = isJSON
+		field.asString = isJSON && opts.Has("string") && canBeString(f.Type)
+		return field, true
+	}
+	return field, true
+}
+
+// isEmpty checks the value should be omitted by omitempty option.
+func (f structField) isEmpty(v reflect.Value) bool {
+	switch {
+	case !f.omitEmpty:
+		return false
+	case !f.jsonEmpty:
+		return v.IsZero()
+	}
+
+	// see encoding/json
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
+
+// canBeString checks `string` option of encoding/json is applicable to the type.
+func canBeString(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.String:
+		return true
+	}
+	return false
+}
+
+// toJSONString converts the value like `string` option of encoding/json.
+func toJSONString(v reflect.Value) interface{} {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return strconv.Quote(v.String())
+	default:
+		b, err := json.Marshal(v.Interface())
+		if err != nil {
+			return v.Interface()
+		}
+		return string(b)
+	}
+}
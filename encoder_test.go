@@ -0,0 +1,56 @@
+package logrus_fluent
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetStructEncoder(t *testing.T) {
+	a := assert.New(t)
+
+	typ := reflect.TypeOf(benchRequest{})
+	enc := getStructEncoder(typ, TagName)
+	a.Equal([]structField{
+		{index: 0, name: "id", basic: true},
+		{index: 1, name: "method", basic: true},
+		{index: 2, name: "path", basic: true},
+		{index: 3, name: "user_agent", omitEmpty: true, basic: true},
+		{index: 4, name: "referer", omitEmpty: true, basic: true},
+		{index: 5, name: "status", basic: true},
+		{index: 6, name: "size", omitEmpty: true, basic: true},
+		{index: 8, name: "Headers"},
+		{index: 9, embedded: true},
+	}, enc.fields)
+	a.False(enc.flat)
+
+	// cached
+	a.True(enc == getStructEncoder(typ, TagName))
+	a.False(enc == getStructEncoder(typ, "json"))
+
+	// embedded non-struct and unexported fields are skipped
+	enc = getStructEncoder(reflect.TypeOf(animal{}), TagName)
+	a.Equal([]structField{{index: 1, name: "Fur", basic: true}}, enc.fields)
+	a.True(enc.flat)
+
+	// duplicated names
+	enc = getStructEncoder(reflect.TypeOf(struct {
+		A string `fluent:"name"`
+		B string `fluent:"name"`
+	}{}), TagName)
+	a.False(enc.flat)
+
+	// basic kind with methods
+	enc = getStructEncoder(reflect.TypeOf(struct {
+		C color
+		D time.Duration
+		E eyes
+	}{}), TagName)
+	a.Equal([]structField{
+		{index: 0, name: "C"},
+		{index: 1, name: "D"},
+		{index: 2, name: "E", basic: true},
+	}, enc.fields)
+}
@@ -0,0 +1,198 @@
+package logrus_fluent
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+const (
+	defaultMaxFrames = 32
+	defaultMaxChain  = 16
+)
+
+// pkgPath is used to skip the frames in this package.
+var pkgPath = reflect.TypeOf(FluentHook{}).PkgPath()
+
+// ErrorEncoder converts error to the value for fluentd logger.
+type ErrorEncoder func(error) interface{}
+
+// Filter is a filter function for AddFilter.
+func (fn ErrorEncoder) Filter(v interface{}) interface{} {
+	if err, ok := v.(error); ok {
+		return fn(err)
+	}
+	return v
+}
+
+// ErrorStackConfig is settings for NewErrorEncoder.
+type ErrorStackConfig struct {
+	MaxFrames    int  // max number of the stack frames. default is 32.
+	MaxChain     int  // max number of the wrapped errors. default is 16.
+	CaptureStack bool // capture the current stack when the error has no stack trace.
+}
+
+var defaultErrorEncoder = NewErrorEncoder(ErrorStackConfig{})
+
+// FilterErrorStack is a filter function to convert error type to structured value.
+//
+//	{
+//	  "message": "...",
+//	  "type": "*errors.errorString",
+//	  "chain": [{"message": "...", "type": "..."}],
+//	  "stack": [{"function": "...", "file": "...", "line": 1}]
+//	}
+func FilterErrorStack(v interface{}) interface{} {
+	return defaultErrorEncoder.Filter(v)
+}
+
+// NewErrorEncoder returns ErrorEncoder which converts error to structured value
+// with unwrapped errors and stack trace.
+func NewErrorEncoder(conf ErrorStackConfig) ErrorEncoder {
+	if conf.MaxFrames == 0 {
+		conf.MaxFrames = defaultMaxFrames
+	}
+	if conf.MaxChain == 0 {
+		conf.MaxChain = defaultMaxChain
+	}
+
+	return func(err error) interface{} {
+		if err == nil {
+			return nil
+		}
+
+		result := map[string]interface{}{
+			"message": err.Error(),
+			"type":    fmt.Sprintf("%T", err),
+		}
+
+		errs := unwrapErrors(err, conf.MaxChain)
+		if len(errs) != 0 {
+			chain := make([]interface{}, len(errs))
+			for i, e := range errs {
+				chain[i] = map[string]interface{}{
+					"message": e.Error(),
+					"type":    fmt.Sprintf("%T", e),
+				}
+			}
+			result["chain"] = chain
+		}
+
+		// use the deepest stack trace.
+		var pcs []uintptr
+		for _, e := range append([]error{err}, errs...) {
+			if v := getStackTrace(e); len(v) != 0 {
+				pcs = v
+			}
+		}
+		if len(pcs) == 0 && conf.CaptureStack {
+			pcs = callers()
+		}
+		if len(pcs) != 0 {
+			result["stack"] = stackFrames(pcs, conf.MaxFrames)
+		}
+		return result
+	}
+}
+
+// unwrapErrors returns wrapped errors by errors.Unwrap and errors.Join in depth-first order.
+func unwrapErrors(err error, max int) []error {
+	var result []error
+	var walk func(error)
+	walk = func(e error) {
+		var children []error
+		switch x := e.(type) {
+		case interface{ Unwrap() []error }:
+			children = x.Unwrap()
+		default:
+			if child := errors.Unwrap(e); child != nil {
+				children = []error{child}
+			}
+		}
+
+		for _, child := range children {
+			if len(result) >= max {
+				return
+			}
+			if child == nil {
+				continue
+			}
+			result = append(result, child)
+			walk(child)
+		}
+	}
+	walk(err)
+	return result
+}
+
+// getStackTrace returns program counters from the error.
+// `StackTrace()` of github.com/pkg/errors and `Callers() []uintptr` are supported.
+func getStackTrace(err error) []uintptr {
+	if x, ok := err.(interface{ Callers() []uintptr }); ok {
+		return x.Callers()
+	}
+
+	m := reflect.ValueOf(err).MethodByName("StackTrace")
+	if !m.IsValid() || m.Type().NumIn() != 0 || m.Type().NumOut() != 1 {
+		return nil
+	}
+	out := m.Type().Out(0)
+	if out.Kind() != reflect.Slice || out.Elem().Kind() != reflect.Uintptr {
+		return nil
+	}
+
+	rv := m.Call(nil)[0]
+	pcs := make([]uintptr, rv.Len())
+	for i := range pcs {
+		pcs[i] = uintptr(rv.Index(i).Uint())
+	}
+	return pcs
+}
+
+// callers returns program counters of the current goroutine
+// except the frames in this package and logrus.
+func callers() []uintptr {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(2, pcs)
+	pcs = pcs[:n]
+
+	for i, pc := range pcs {
+		f, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+		if !isInternalFrame(f.Function) {
+			return pcs[i:]
+		}
+	}
+	return nil
+}
+
+func isInternalFrame(fn string) bool {
+	return strings.HasPrefix(fn, pkgPath+".") ||
+		strings.HasPrefix(fn, "github.com/sirupsen/logrus.")
+}
+
+// stackFrames converts program counters to the frames.
+func stackFrames(pcs []uintptr, max int) []interface{} {
+	var result []interface{}
+	frames := runtime.CallersFrames(pcs)
+	for {
+		f, more := frames.Next()
+		if f.Function != "" || f.File != "" {
+			result = append(result, map[string]interface{}{
+				"function": f.Function,
+				"file":     f.File,
+				"line":     f.Line,
+			})
+		}
+		if !more || (max > 0 && len(result) >= max) {
+			return result
+		}
+	}
+}
+
+// SetErrorEncoder sets the encoder for error values in the log fields.
+// It is used for the error in a struct or a map, after filter functions.
+func (hook *FluentHook) SetErrorEncoder(fn ErrorEncoder) {
+	hook.converter.ErrorEncoder = fn
+}
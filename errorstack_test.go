@@ -0,0 +1,104 @@
+package logrus_fluent
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// stackFrame and stackError mimic github.com/pkg/errors.
+type stackFrame uintptr
+
+type stackError struct {
+	msg   string
+	stack []uintptr
+}
+
+func newStackError(msg string) error {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(1, pcs)
+	return &stackError{msg: msg, stack: pcs[:n]}
+}
+
+func (e *stackError) Error() string { return e.msg }
+
+func (e *stackError) StackTrace() []stackFrame {
+	frames := make([]stackFrame, len(e.stack))
+	for i, pc := range e.stack {
+		frames[i] = stackFrame(pc)
+	}
+	return frames
+}
+
+func TestFilterErrorStack(t *testing.T) {
+	a := assert.New(t)
+
+	base := newStackError("base error")
+	other := errors.New("other error")
+	err := fmt.Errorf("wrapped: %w", errors.Join(base, other))
+
+	result, ok := FilterErrorStack(err).(map[string]interface{})
+	a.True(ok)
+	a.Equal(err.Error(), result["message"])
+	a.Equal("*fmt.wrapError", result["type"])
+
+	chain, ok := result["chain"].([]interface{})
+	a.True(ok)
+	a.Len(chain, 3)
+	a.Equal("base error", chain[1].(map[string]interface{})["message"])
+	a.Equal("*logrus_fluent.stackError", chain[1].(map[string]interface{})["type"])
+	a.Equal("other error", chain[2].(map[string]interface{})["message"])
+
+	stack, ok := result["stack"].([]interface{})
+	a.True(ok)
+	a.NotEmpty(stack)
+	frame := stack[0].(map[string]interface{})
+	a.True(strings.HasSuffix(frame["function"].(string), "newStackError"), "%v", frame["function"])
+	a.Contains(frame["file"], "errorstack_test.go")
+
+	// not error
+	a.Equal("value", FilterErrorStack("value"))
+}
+
+func TestNewErrorEncoder(t *testing.T) {
+	a := assert.New(t)
+
+	err := errors.New("no stack")
+	result := NewErrorEncoder(ErrorStackConfig{})(err).(map[string]interface{})
+	a.NotContains(result, "stack")
+	a.NotContains(result, "chain")
+
+	result = NewErrorEncoder(ErrorStackConfig{
+		MaxFrames:    2,
+		CaptureStack: true,
+	})(err).(map[string]interface{})
+	stack := result["stack"].([]interface{})
+	a.Len(stack, 2)
+	a.False(isInternalFrame(stack[0].(map[string]interface{})["function"].(string)), "frames in this package should be skipped")
+
+	wrapped := fmt.Errorf("3: %w", fmt.Errorf("2: %w", fmt.Errorf("1: %w", err)))
+	result = NewErrorEncoder(ErrorStackConfig{MaxChain: 2})(wrapped).(map[string]interface{})
+	a.Len(result["chain"], 2)
+}
+
+func TestConverterErrorEncoder(t *testing.T) {
+	a := assert.New(t)
+
+	err := newStackError("the error")
+	c := Converter{
+		TagName:      TagName,
+		ErrorEncoder: NewErrorEncoder(ErrorStackConfig{MaxFrames: 1}),
+	}
+	result := c.ConvertToValue(map[string]interface{}{"error": err})
+
+	r, ok := result.(map[string]interface{})
+	a.True(ok)
+	e, ok := r["error"].(map[string]interface{})
+	a.True(ok)
+	a.Equal("the error", e["message"])
+	a.Len(e["stack"], 1)
+}
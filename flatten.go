@@ -0,0 +1,79 @@
+package logrus_fluent
+
+import "strconv"
+
+// defaultFlattenSeparator is used when FlattenConfig.Separator is empty.
+const defaultFlattenSeparator = "."
+
+// FlattenConfig is settings for flattening nested fields into dotted keys.
+//
+//	{"user": {"id": 1, "items": [{"id": 2}]}}
+//	=> {"user.id": 1, "user.items.0.id": 2} (with Arrays)
+type FlattenConfig struct {
+	Separator string   // separator for the keys. default is ".".
+	MaxDepth  int      // max number of the segments in a key. deeper value is kept as it is. unlimited if 0.
+	Arrays    bool     // slices are flattened with index keys if true.
+	Fields    []string // only these top-level fields are flattened. all fields if empty.
+}
+
+// Flatten flattens nested maps (and slices) in the data.
+func (c FlattenConfig) Flatten(data map[string]interface{}) map[string]interface{} {
+	sep := c.Separator
+	if sep == "" {
+		sep = defaultFlattenSeparator
+	}
+
+	var targets map[string]struct{}
+	if len(c.Fields) != 0 {
+		targets = make(map[string]struct{}, len(c.Fields))
+		for _, f := range c.Fields {
+			targets[f] = struct{}{}
+		}
+	}
+
+	result := make(map[string]interface{}, len(data))
+	for k, v := range data {
+		if targets != nil {
+			if _, ok := targets[k]; !ok {
+				result[k] = v
+				continue
+			}
+		}
+		c.flattenValue(result, k, v, sep, 1)
+	}
+	return result
+}
+
+func (c FlattenConfig) flattenValue(result map[string]interface{}, key string, v interface{}, sep string, depth int) {
+	if c.MaxDepth > 0 && depth >= c.MaxDepth {
+		result[key] = v
+		return
+	}
+
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		if len(vv) == 0 {
+			result[key] = v
+			return
+		}
+		for k, child := range vv {
+			c.flattenValue(result, key+sep+k, child, sep, depth+1)
+		}
+	case []interface{}:
+		if !c.Arrays || len(vv) == 0 {
+			result[key] = v
+			return
+		}
+		for i, child := range vv {
+			c.flattenValue(result, key+sep+strconv.Itoa(i), child, sep, depth+1)
+		}
+	default:
+		result[key] = v
+	}
+}
+
+// SetFlatten sets settings for flattening nested fields.
+// Flattening is disabled when conf is nil.
+func (hook *FluentHook) SetFlatten(conf *FlattenConfig) {
+	hook.flatten = conf
+}
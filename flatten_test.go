@@ -0,0 +1,107 @@
+package logrus_fluent
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlatten(t *testing.T) {
+	a := assert.New(t)
+
+	newData := func() map[string]interface{} {
+		return map[string]interface{}{
+			"value": fieldValue,
+			"user": map[string]interface{}{
+				"id": 1,
+				"profile": map[string]interface{}{
+					"name": "alice",
+				},
+				"items": []interface{}{
+					map[string]interface{}{"id": 2},
+					3,
+				},
+				"empty": map[string]interface{}{},
+			},
+			"request": map[string]interface{}{
+				"path": "/",
+			},
+		}
+	}
+
+	tests := []struct {
+		conf     FlattenConfig
+		expected map[string]interface{}
+	}{
+		{
+			conf: FlattenConfig{},
+			expected: map[string]interface{}{
+				"value":             fieldValue,
+				"user.id":           1,
+				"user.profile.name": "alice",
+				"user.items":        []interface{}{map[string]interface{}{"id": 2}, 3},
+				"user.empty":        map[string]interface{}{},
+				"request.path":      "/",
+			},
+		},
+		{
+			conf: FlattenConfig{Separator: "_", Arrays: true},
+			expected: map[string]interface{}{
+				"value":             fieldValue,
+				"user_id":           1,
+				"user_profile_name": "alice",
+				"user_items_0_id":   2,
+				"user_items_1":      3,
+				"user_empty":        map[string]interface{}{},
+				"request_path":      "/",
+			},
+		},
+		{
+			conf: FlattenConfig{MaxDepth: 2, Arrays: true},
+			expected: map[string]interface{}{
+				"value":        fieldValue,
+				"user.id":      1,
+				"user.profile": map[string]interface{}{"name": "alice"},
+				"user.items":   []interface{}{map[string]interface{}{"id": 2}, 3},
+				"user.empty":   map[string]interface{}{},
+				"request.path": "/",
+			},
+		},
+		{
+			conf: FlattenConfig{Fields: []string{"request"}},
+			expected: map[string]interface{}{
+				"value":        fieldValue,
+				"user":         newData()["user"],
+				"request.path": "/",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		a.Equal(tt.expected, tt.conf.Flatten(newData()), "%#v", tt.conf)
+	}
+}
+
+func TestSetFlatten(t *testing.T) {
+	a := assert.New(t)
+
+	hook := NewHook(testHOST, -1)
+	hook.SetFlatten(&FlattenConfig{Arrays: true})
+
+	entry := logrus.NewEntry(logrus.New())
+	entry.Data = logrus.Fields{
+		"tag":      fieldTag,
+		"creature": Creature{Name: "cat"},
+		"list":     []string{"a", "b"},
+	}
+	entry.Message = entryMessage
+
+	_, data := hook.getTagAndData(entry)
+	result, ok := hook.encodeData(data).(map[string]interface{})
+	a.True(ok)
+	a.Equal("cat", result["creature.Name"])
+	a.Equal("a", result["list.0"])
+	a.Equal("b", result["list.1"])
+	a.NotContains(result, "creature")
+}
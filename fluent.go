@@ -1,6 +1,8 @@
 package logrus_fluent
 
 import (
+	"errors"
+
 	"github.com/fluent/fluent-logger-golang/fluent"
 	"github.com/sirupsen/logrus"
 )
@@ -20,6 +22,8 @@ const (
 	// MessageField is logrus field name used as message.
 	// If missing in the log fields, entry.Message is set to this field.
 	MessageField = "message"
+	// LevelField is field name used as log level.
+	LevelField = "level"
 )
 
 var defaultLevels = []logrus.Level{
@@ -42,9 +46,35 @@ type FluentHook struct {
 	tag    *string
 
 	messageField string
+	levelField   string
+	levelEncoder LevelEncoder
+	timeField    string
 	ignoreFields map[string]struct{}
+	renameFields map[string]string
 	filters      map[string]func(interface{}) interface{}
 	customizers  []func(entry *logrus.Entry, data logrus.Fields)
+
+	defaultFields            map[string]FieldProvider
+	keepIgnoredDefaultFields bool
+	contextExtractors        []ContextExtractor
+
+	fieldsKey       string
+	collisionPolicy CollisionPolicy
+	collisionPrefix string
+	flatten         *FlattenConfig
+	omitEmpty       *OmitEmptyConfig
+	sizeLimit       *SizeLimitConfig
+	chunk           *ChunkConfig
+
+	metadataKey string
+	metadata    map[string]interface{}
+	kubernetes  *KubernetesEnricher
+	caller      *CallerConfig
+	trace       *TraceConfig
+	converter   Converter
+
+	swallowPanic   bool
+	directEncoding bool
 }
 
 // New returns initialized logrus hook for fluentd with persistent fluentd logger.
@@ -71,8 +101,14 @@ func NewWithConfig(conf Config) (*FluentHook, error) {
 		Fluent:       fd,
 		conf:         conf,
 		levels:       conf.LogLevels,
+		levelField:   LevelField,
+		converter:    newConverter(conf),
 		ignoreFields: make(map[string]struct{}),
+		renameFields: make(map[string]string),
 		filters:      make(map[string]func(interface{}) interface{}),
+
+		defaultFields:            make(map[string]FieldProvider),
+		keepIgnoredDefaultFields: conf.KeepIgnoredDefaults,
 	}
 	// set default values
 	if len(hook.levels) == 0 {
@@ -82,15 +118,59 @@ func NewWithConfig(conf Config) (*FluentHook, error) {
 		tag := conf.DefaultTag
 		hook.tag = &tag
 	}
+	if conf.DefaultFieldPreset != nil {
+		hook.UseFieldPreset(*conf.DefaultFieldPreset)
+	}
 	if conf.DefaultMessageField != "" {
 		hook.messageField = conf.DefaultMessageField
 	}
+	if conf.DefaultLevelField != "" {
+		hook.levelField = conf.DefaultLevelField
+	}
+	if conf.DefaultLevelEncoder != nil {
+		hook.levelEncoder = conf.DefaultLevelEncoder
+	}
+	if conf.DisableLevelField {
+		hook.levelField = ""
+	}
+	if conf.DefaultTimeField != "" {
+		hook.timeField = conf.DefaultTimeField
+	}
+	if conf.DefaultFieldsKey != "" {
+		hook.fieldsKey = conf.DefaultFieldsKey
+	}
+	hook.collisionPolicy = conf.CollisionPolicy
+	hook.collisionPrefix = conf.CollisionPrefix
+	hook.flatten = conf.Flatten
+	hook.omitEmpty = conf.OmitEmpty
+	hook.sizeLimit = conf.SizeLimit
+	hook.chunk = conf.Chunk
+	hook.SetMetadata(conf.Metadata)
+	hook.SetCaller(conf.Caller)
+	hook.SetTraceCorrelation(conf.Trace)
+	hook.swallowPanic = conf.SwallowPanic
+	hook.directEncoding = conf.DirectEncoding
+	if conf.Kubernetes != nil {
+		hook.kubernetes = NewKubernetesEnricher(*conf.Kubernetes)
+	}
 	for k, v := range conf.DefaultIgnoreFields {
 		hook.ignoreFields[k] = v
 	}
+	for k, v := range conf.DefaultRenameFields {
+		hook.renameFields[k] = v
+	}
 	for k, v := range conf.DefaultFilters {
 		hook.filters[k] = v
 	}
+	for k, v := range conf.DefaultFields {
+		hook.AddDefaultField(k, v)
+	}
+	for k, fn := range conf.DefaultFieldProviders {
+		hook.AddFieldProvider(k, fn)
+	}
+	for _, fn := range conf.ContextExtractors {
+		hook.AddContextExtractor(fn)
+	}
 
 	return hook, nil
 }
@@ -107,6 +187,17 @@ func NewHook(host string, port int) *FluentHook {
 	return hook
 }
 
+// Close closes the fluentd logger and stops watching the kubernetes metadata.
+func (hook *FluentHook) Close() error {
+	if hook.kubernetes != nil {
+		hook.kubernetes.Close()
+	}
+	if hook.Fluent == nil {
+		return nil
+	}
+	return hook.Fluent.Close()
+}
+
 // Levels returns logging level to fire this hook.
 func (hook *FluentHook) Levels() []logrus.Level {
 	return hook.levels
@@ -136,11 +227,34 @@ func (hook *FluentHook) SetMessageField(messageField string) {
 	hook.messageField = messageField
 }
 
+// SetLevelField sets custom level field.
+// level field is not added into the fields when it's empty.
+func (hook *FluentHook) SetLevelField(levelField string) {
+	hook.levelField = levelField
+}
+
+// SetLevelEncoder sets custom encoder for level field.
+func (hook *FluentHook) SetLevelEncoder(fn LevelEncoder) {
+	hook.levelEncoder = fn
+}
+
+// SetTimeField sets field name for entry.Time.
+// entry.Time is not added into the fields when it's empty.
+func (hook *FluentHook) SetTimeField(timeField string) {
+	hook.timeField = timeField
+}
+
 // AddIgnore adds field name to ignore.
 func (hook *FluentHook) AddIgnore(name string) {
 	hook.ignoreFields[name] = struct{}{}
 }
 
+// AddRename adds field name to rename.
+// Renaming is applied after filters and before customizers.
+func (hook *FluentHook) AddRename(from, to string) {
+	hook.renameFields[from] = to
+}
+
 // AddFilter adds a custom filter function.
 func (hook *FluentHook) AddFilter(name string, fn func(interface{}) interface{}) {
 	hook.filters[name] = fn
@@ -167,32 +281,88 @@ func (hook *FluentHook) Fire(entry *logrus.Entry) error {
 		defer logger.Close()
 	}
 
+	tag, data := hook.getTagAndData(entry)
+	if hook.useDirectEncoding(logger) {
+		return hook.postEncoded(logger, tag, entry.Time, data)
+	}
+	// chunks are sent under the same tag in order.
+	var limitErr error
+	for _, record := range hook.chunkRecords(hook.encodeData(data)) {
+		record, err = hook.limitRecord(record)
+		switch {
+		case errors.Is(err, ErrRecordTooLarge):
+			limitErr = err // the minimal record is sent instead.
+		case err != nil:
+			return err
+		}
+		if err = logger.PostWithTime(tag, entry.Time, hook.orderRecord(record)); err != nil {
+			return err
+		}
+	}
+	return limitErr
+}
+
+// encodeData converts data fields to the value for fluentd logger.
+func (hook *FluentHook) encodeData(data logrus.Fields) interface{} {
+	fluentData := hook.converter.ConvertToValue(data)
+	m, ok := fluentData.(map[string]interface{})
+	if !ok {
+		return fluentData
+	}
+
+	if hook.omitEmpty != nil {
+		m = hook.omitEmpty.omitEmpty(m, hook.ownFieldKeys())
+	}
+	if hook.flatten != nil {
+		m = hook.flatten.Flatten(m)
+	}
+	return m
+}
+
+// getTagAndData creates fluentd tag and data fields from log entry.
+func (hook *FluentHook) getTagAndData(entry *logrus.Entry) (string, logrus.Fields) {
 	// Create a map for passing to FluentD
-	data := make(logrus.Fields)
+	// (priority: entry.Data > entry.Context > default fields)
+	fields := make(logrus.Fields)
+	hook.addDefaultFields(entry, fields)
+	hook.addContextFields(entry, fields)
 	for k, v := range entry.Data {
 		if _, ok := hook.ignoreFields[k]; ok {
 			continue
 		}
-		if fn, ok := hook.filters[k]; ok {
-			v = fn(v)
-		}
-		data[k] = v
+		hook.addField(fields, k, v)
 	}
+	tag := hook.getTagAndDel(entry, fields)
 
-	setLevelString(entry, data)
-	tag := hook.getTagAndDel(entry, data)
+	// fields set by the hook
+	data := make(logrus.Fields)
+	hook.setLevel(entry, data)
+	hook.setTime(entry, data)
+	hook.setMetadata(data)
+	hook.setKubernetes(data)
+	hook.setCaller(entry, data)
+	hook.setTrace(entry, data)
 	if tag != entry.Message {
 		hook.setMessage(entry, data)
 	}
+	data = hook.mergeFields(data, fields)
 
 	// modify data to your own needs.
 	for _, fn := range hook.customizers {
 		fn(entry, data)
 	}
+	return tag, data
+}
 
-	fluentData := ConvertToValue(data, TagName)
-	err = logger.PostWithTime(tag, entry.Time, fluentData)
-	return err
+// addField adds the field after applying filter and rename.
+func (hook *FluentHook) addField(fields logrus.Fields, k string, v interface{}) {
+	if fn, ok := hook.filters[k]; ok {
+		v = fn(v)
+	}
+	if name, ok := hook.renameFields[k]; ok {
+		k = name
+	}
+	fields[k] = v
 }
 
 // getTagAndDel extracts tag data from log entry and custom log fields.
@@ -221,10 +391,6 @@ func (hook *FluentHook) getTagAndDel(entry *logrus.Entry, data logrus.Fields) st
 }
 
 func (hook *FluentHook) setMessage(entry *logrus.Entry, data logrus.Fields) {
-	if _, ok := data[hook.messageField]; ok {
-		return
-	}
-
 	var v interface{} = entry.Message
 	if fn, ok := hook.filters[hook.messageField]; ok {
 		v = fn(v)
@@ -232,6 +398,20 @@ func (hook *FluentHook) setMessage(entry *logrus.Entry, data logrus.Fields) {
 	data[hook.messageField] = v
 }
 
-func setLevelString(entry *logrus.Entry, data logrus.Fields) {
-	data["level"] = entry.Level.String()
+func (hook *FluentHook) setLevel(entry *logrus.Entry, data logrus.Fields) {
+	if hook.levelField == "" {
+		return
+	}
+	if hook.levelEncoder == nil {
+		data[hook.levelField] = LevelEncoderLowercase(entry.Level)
+		return
+	}
+	data[hook.levelField] = hook.levelEncoder(entry.Level)
+}
+
+func (hook *FluentHook) setTime(entry *logrus.Entry, data logrus.Fields) {
+	if hook.timeField == "" {
+		return
+	}
+	data[hook.timeField] = hook.converter.encodeTime(entry.Time)
 }
@@ -7,6 +7,7 @@ import (
 	"net"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
@@ -250,6 +251,59 @@ func TestAddIgnore(t *testing.T) {
 	}
 }
 
+func TestAddRename(t *testing.T) {
+	hook := FluentHook{
+		renameFields: make(map[string]string),
+	}
+
+	hook.AddRename("foo", "bar")
+	hook.AddRename("baz", "qux")
+	switch {
+	case len(hook.renameFields) != 2:
+		t.Errorf("hook.renameFields has %d length, but %d", 2, len(hook.renameFields))
+	case hook.renameFields["foo"] != "bar":
+		t.Errorf("hook.renameFields[foo] should be bar, but %s", hook.renameFields["foo"])
+	case hook.renameFields["baz"] != "qux":
+		t.Errorf("hook.renameFields[baz] should be qux, but %s", hook.renameFields["baz"])
+	}
+}
+
+func TestGetTagAndDataWithRename(t *testing.T) {
+	a := assert.New(t)
+
+	hook := NewHook(testHOST, -1)
+	hook.SetLevelField("severity")
+	hook.SetTimeField("@timestamp")
+	hook.SetMessageField("msg")
+	hook.AddRename("user", "user_name")
+	hook.AddFilter("user", func(v interface{}) interface{} {
+		return "filtered " + v.(string)
+	})
+	hook.AddCustomizer(func(entry *logrus.Entry, data logrus.Fields) {
+		_, ok := data["user_name"]
+		a.True(ok, "rename should be applied before customizers")
+	})
+
+	entry := logrus.NewEntry(logrus.New())
+	entry.Data = logrus.Fields{
+		"tag":  fieldTag,
+		"user": "alice",
+	}
+	entry.Level = logrus.WarnLevel
+	entry.Message = entryMessage
+	entry.Time = time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC)
+
+	tag, data := hook.getTagAndData(entry)
+	a.Equal(fieldTag, tag)
+	a.Equal("filtered alice", data["user_name"])
+	a.Equal("warning", data["severity"])
+	a.Equal("2020-01-02T03:04:05.000000006Z", data["@timestamp"])
+	a.Equal(entryMessage, data["msg"])
+	a.NotContains(data, "user")
+	a.NotContains(data, "level")
+	a.NotContains(data, "message")
+}
+
 func TestAddFilter(t *testing.T) {
 	hook := FluentHook{
 		filters: make(map[string]func(interface{}) interface{}),
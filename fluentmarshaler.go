@@ -0,0 +1,104 @@
+package logrus_fluent
+
+import (
+	"reflect"
+	"sync"
+)
+
+// FluentMarshaler is implemented by types that convert themselves to fluentd record values.
+// Converter uses MarshalFluent() instead of reflection at every nesting level.
+//
+// The returned value is converted again by Converter with the same options.
+// The values in map[string]interface{} and []interface{} are converted too.
+type FluentMarshaler interface {
+	MarshalFluent() interface{}
+}
+
+var fluentMarshalerType = reflect.TypeOf((*FluentMarshaler)(nil)).Elem()
+
+// ptrFluentMarshalers caches whether the pointer type of non-pointer type implements FluentMarshaler.
+var ptrFluentMarshalers sync.Map // map[reflect.Type]bool
+
+// isPtrFluentMarshaler checks MarshalFluent() is implemented with a pointer receiver.
+func isPtrFluentMarshaler(t reflect.Type) bool {
+	if v, ok := ptrFluentMarshalers.Load(t); ok {
+		return v.(bool)
+	}
+	ok := t.Kind() != reflect.Ptr && t.Kind() != reflect.Interface && reflect.PtrTo(t).Implements(fluentMarshalerType)
+	ptrFluentMarshalers.Store(t, ok)
+	return ok
+}
+
+// asFluentMarshaler returns FluentMarshaler of the value or the pointer to the copied value.
+// A nil pointer is never used as FluentMarshaler.
+func asFluentMarshaler(p interface{}) (FluentMarshaler, bool) {
+	if p == nil {
+		return nil, false
+	}
+	if m, ok := p.(FluentMarshaler); ok {
+		if isNilPointer(p) {
+			return nil, false
+		}
+		return m, true
+	}
+
+	rv := reflect.ValueOf(p)
+	if !isPtrFluentMarshaler(rv.Type()) {
+		return nil, false
+	}
+	pv := reflect.New(rv.Type())
+	pv.Elem().Set(rv)
+	return pv.Interface().(FluentMarshaler), true
+}
+
+// marshalFluent converts the value by FluentMarshaler.
+// The returned value is converted with the same state, so the cycles and the depth are tracked across MarshalFluent().
+func (c Converter) marshalFluent(p interface{}, st *convertState) (interface{}, bool) {
+	m, ok := asFluentMarshaler(p)
+	if !ok {
+		return nil, false
+	}
+
+	v := m.MarshalFluent()
+	if v == nil {
+		return nil, true
+	}
+
+	if st == nil {
+		st = c.newConvertState()
+	}
+	if st.depth >= c.maxDepth() {
+		return MaxDepthMarker, true
+	}
+
+	// avoid infinite recursion when the value returns itself.
+	if t, vt := reflect.TypeOf(p), reflect.TypeOf(v); vt == t || reflect.PtrTo(vt) == t || vt == reflect.PtrTo(t) {
+		return c.convertValue(v, st), true
+	}
+
+	key, ok := getVisitKey(reflect.ValueOf(p))
+	if ok {
+		if !st.visit(key) {
+			return CycleMarker, true
+		}
+		defer st.leave(key)
+	}
+	st.depth++
+	defer func() { st.depth-- }()
+
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(vv))
+		for k, x := range vv {
+			result[k] = c.convert(x, st)
+		}
+		return result, true
+	case []interface{}:
+		result := make([]interface{}, len(vv))
+		for i, x := range vv {
+			result[i] = c.convert(x, st)
+		}
+		return result, true
+	}
+	return c.convert(v, st), true
+}
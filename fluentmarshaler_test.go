@@ -0,0 +1,149 @@
+package logrus_fluent
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type bigRequest struct {
+	ID    int
+	Route string
+	Body  []byte
+}
+
+func (r bigRequest) MarshalFluent() interface{} {
+	return map[string]interface{}{"id": r.ID, "route": r.Route}
+}
+
+type ptrSummary struct {
+	ID int
+}
+
+func (s *ptrSummary) MarshalFluent() interface{} {
+	return struct {
+		SummaryID int       `fluent:"summary_id"`
+		At        time.Time `fluent:"at"`
+	}{s.ID, time.Unix(0, 0).UTC()}
+}
+
+type selfMarshaler struct {
+	Name string `fluent:"name"`
+}
+
+func (s selfMarshaler) MarshalFluent() interface{} { return s }
+
+type loopA struct{}
+type loopB struct{}
+
+func (loopA) MarshalFluent() interface{} { return loopB{} }
+func (loopB) MarshalFluent() interface{} { return loopA{} }
+
+type summaryNode struct {
+	Name string
+	Next *summaryNode
+}
+
+func (n *summaryNode) MarshalFluent() interface{} {
+	return map[string]interface{}{"name": n.Name, "next": n.Next}
+}
+
+type pet struct {
+	Name string `fluent:"name"`
+}
+
+type summaryList struct {
+	Items []interface{}
+}
+
+func (l summaryList) MarshalFluent() interface{} { return l.Items }
+
+func TestFluentMarshaler(t *testing.T) {
+	a := assert.New(t)
+
+	req := bigRequest{ID: 1, Route: "/users", Body: make([]byte, 1024)}
+	summary := map[string]interface{}{"id": 1, "route": "/users"}
+	ptrResult := map[string]interface{}{"summary_id": 2, "at": "1970-01-01T00:00:00Z"}
+	var nilReq *bigRequest
+	var nilSummary *ptrSummary
+
+	tests := []struct {
+		name     string
+		value    interface{}
+		expected interface{}
+	}{
+		{"value", req, summary},
+		{"pointer", &req, summary},
+		{"nil pointer", nilReq, nil},
+		{"pointer receiver", ptrSummary{ID: 2}, ptrResult},
+		{"pointer receiver with pointer", &ptrSummary{ID: 2}, ptrResult},
+		{"nil pointer receiver", nilSummary, nil},
+		{"map element", map[string]interface{}{"req": req}, map[string]interface{}{"req": summary}},
+		{"typed map element", map[string]ptrSummary{"s": {ID: 2}}, map[string]interface{}{"s": ptrResult}},
+		{"slice element", []bigRequest{req}, []interface{}{summary}},
+		{"slice pointer element", []*ptrSummary{{ID: 2}, nil}, []interface{}{ptrResult, nil}},
+		{"struct field", struct {
+			Req     bigRequest  `fluent:"req"`
+			Summary ptrSummary  `fluent:"summary"`
+			Nil     *ptrSummary `fluent:"nil"`
+		}{Req: req, Summary: ptrSummary{ID: 2}}, map[string]interface{}{"req": summary, "summary": ptrResult, "nil": nil}},
+		{"returns itself", selfMarshaler{Name: "self"}, map[string]interface{}{"name": "self"}},
+		{"loop", loopA{}, MaxDepthMarker},
+	}
+
+	for _, tt := range tests {
+		a.Equal(tt.expected, ConvertToValue(tt.value, TagName), tt.name)
+	}
+}
+
+func TestFluentMarshalerNestedValues(t *testing.T) {
+	a := assert.New(t)
+
+	c := Converter{TagName: TagName, TimeEncoding: TimeUnix, MaxDepth: 3}
+	tm := time.Unix(1577934245, 0)
+	summary := map[string]interface{}{
+		"struct": ptrSummary{ID: 2},
+		"error":  errors.New("err"),
+		"time":   tm,
+		"nested": map[string]interface{}{"creature": &pet{Name: "cat"}},
+	}
+	req := bigRequest{ID: 1}
+	list := summaryList{Items: []interface{}{tm, pet{Name: "dog"}}}
+
+	n := &summaryNode{Name: "a"}
+	n.Next = n
+	deep := &summaryNode{Name: "1", Next: &summaryNode{Name: "2", Next: &summaryNode{Name: "3", Next: &summaryNode{Name: "4"}}}}
+
+	tests := []struct {
+		name     string
+		value    interface{}
+		expected interface{}
+	}{
+		{"map", fluentMarshalerFunc(func() interface{} { return summary }), map[string]interface{}{
+			"struct": map[string]interface{}{"summary_id": 2, "at": int64(0)},
+			"error":  "err",
+			"time":   int64(1577934245),
+			"nested": map[string]interface{}{"creature": map[string]interface{}{"name": "cat"}},
+		}},
+		{"slice", list, []interface{}{int64(1577934245), map[string]interface{}{"name": "dog"}}},
+		{"map with basic values", req, map[string]interface{}{"id": 1, "route": ""}},
+		{"cycle", n, map[string]interface{}{"name": "a", "next": CycleMarker}},
+		{"max depth", deep, map[string]interface{}{"name": "1", "next": map[string]interface{}{"name": "2", "next": map[string]interface{}{"name": "3", "next": MaxDepthMarker}}}},
+	}
+
+	for _, tt := range tests {
+		a.Equal(tt.expected, c.ConvertToValue(tt.value), tt.name)
+
+		b, err := c.AppendMsgpack(nil, tt.value)
+		a.NoError(err, tt.name)
+		expected, err := c.appendConverted(nil, tt.expected)
+		a.NoError(err, tt.name)
+		a.Equal(decodeMsgpack(t, expected), decodeMsgpack(t, b), tt.name)
+	}
+}
+
+type fluentMarshalerFunc func() interface{}
+
+func (f fluentMarshalerFunc) MarshalFluent() interface{} { return f() }
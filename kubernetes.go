@@ -0,0 +1,205 @@
+package logrus_fluent
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// KubernetesKey is field name for the kubernetes metadata.
+	KubernetesKey = "kubernetes"
+
+	defaultPodInfoDir = "/etc/podinfo"
+)
+
+// environment variables for the kubernetes metadata set by downward API.
+const (
+	EnvPodName       = "POD_NAME"
+	EnvPodNamespace  = "POD_NAMESPACE"
+	EnvPodUID        = "POD_UID"
+	EnvPodIP         = "POD_IP"
+	EnvNodeName      = "NODE_NAME"
+	EnvContainerName = "CONTAINER_NAME"
+)
+
+// KubernetesConfig is settings for the kubernetes metadata from downward API.
+//
+// The metadata is read from the environment variables and the files in PodInfoDir.
+// (name, namespace, uid, labels, annotations)
+type KubernetesConfig struct {
+	PodInfoDir    string        // default is "/etc/podinfo".
+	WatchInterval time.Duration // labels and annotations files are reloaded on change if set.
+}
+
+// KubernetesEnricher holds the kubernetes metadata which has the same shape
+// as fluentd's kubernetes_metadata filter.
+type KubernetesEnricher struct {
+	conf     KubernetesConfig
+	metadata atomic.Value
+
+	modTimes  map[string]time.Time
+	stop      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewKubernetesEnricher reads the kubernetes metadata and starts watching the files.
+func NewKubernetesEnricher(conf KubernetesConfig) *KubernetesEnricher {
+	if conf.PodInfoDir == "" {
+		conf.PodInfoDir = defaultPodInfoDir
+	}
+
+	e := &KubernetesEnricher{
+		conf: conf,
+		stop: make(chan struct{}),
+	}
+	e.modTimes = e.getModTimes()
+	e.load()
+	if conf.WatchInterval > 0 {
+		ticker := time.NewTicker(conf.WatchInterval)
+		go func() {
+			defer ticker.Stop()
+			e.watch(ticker.C)
+		}()
+	}
+	return e
+}
+
+// Metadata returns the kubernetes metadata.
+func (e *KubernetesEnricher) Metadata() map[string]interface{} {
+	m, _ := e.metadata.Load().(map[string]interface{})
+	return m
+}
+
+// Close stops watching the files.
+func (e *KubernetesEnricher) Close() {
+	e.closeOnce.Do(func() {
+		close(e.stop)
+	})
+}
+
+func (e *KubernetesEnricher) load() {
+	m := make(map[string]interface{})
+	setNonEmpty := func(key, env, file string) {
+		v := os.Getenv(env)
+		if v == "" && file != "" {
+			v = e.readFile(file)
+		}
+		if v != "" {
+			m[key] = v
+		}
+	}
+	setNonEmpty("pod_name", EnvPodName, "name")
+	setNonEmpty("namespace_name", EnvPodNamespace, "namespace")
+	setNonEmpty("pod_id", EnvPodUID, "uid")
+	setNonEmpty("pod_ip", EnvPodIP, "")
+	setNonEmpty("host", EnvNodeName, "")
+	setNonEmpty("container_name", EnvContainerName, "")
+
+	if labels := parsePodInfo(e.readFile("labels")); len(labels) != 0 {
+		m["labels"] = labels
+	}
+	if annotations := parsePodInfo(e.readFile("annotations")); len(annotations) != 0 {
+		m["annotations"] = annotations
+	}
+	e.metadata.Store(m)
+}
+
+func (e *KubernetesEnricher) readFile(name string) string {
+	b, err := os.ReadFile(filepath.Join(e.conf.PodInfoDir, name))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
+
+// watch reloads the modified files on every tick until Close is called.
+func (e *KubernetesEnricher) watch(tick <-chan time.Time) {
+	for {
+		select {
+		case <-e.stop:
+			return
+		case <-tick:
+			e.reloadIfModified()
+		}
+	}
+}
+
+// reloadIfModified reloads the metadata when the labels or annotations files are modified.
+func (e *KubernetesEnricher) reloadIfModified() bool {
+	modTimes := e.getModTimes()
+	if isSameModTimes(e.modTimes, modTimes) {
+		return false
+	}
+	e.modTimes = modTimes
+	e.load()
+	return true
+}
+
+// getModTimes returns modified time of the labels and annotations files.
+func (e *KubernetesEnricher) getModTimes() map[string]time.Time {
+	result := make(map[string]time.Time)
+	for _, name := range []string{"labels", "annotations"} {
+		if fi, err := os.Stat(filepath.Join(e.conf.PodInfoDir, name)); err == nil {
+			result[name] = fi.ModTime()
+		}
+	}
+	return result
+}
+
+func isSameModTimes(a, b map[string]time.Time) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if !v.Equal(b[k]) {
+			return false
+		}
+	}
+	return true
+}
+
+// parsePodInfo parses labels or annotations file of downward API.
+//
+//	app="my-app"
+//	tier="backend"
+func parsePodInfo(content string) map[string]interface{} {
+	result := make(map[string]interface{})
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		idx := strings.Index(line, "=")
+		if idx < 1 {
+			continue
+		}
+
+		value := line[idx+1:]
+		if v, err := strconv.Unquote(value); err == nil {
+			value = v
+		}
+		result[line[:idx]] = value
+	}
+	return result
+}
+
+// SetKubernetesEnricher sets the kubernetes metadata into every log.
+func (hook *FluentHook) SetKubernetesEnricher(e *KubernetesEnricher) {
+	hook.kubernetes = e
+}
+
+// setKubernetes adds the kubernetes metadata into data fields.
+func (hook *FluentHook) setKubernetes(data logrus.Fields) {
+	if hook.kubernetes == nil {
+		return
+	}
+	if m := hook.kubernetes.Metadata(); len(m) != 0 {
+		data[KubernetesKey] = m
+	}
+}
@@ -0,0 +1,120 @@
+package logrus_fluent
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePodInfo(t *testing.T) {
+	a := assert.New(t)
+
+	result := parsePodInfo(`app="my-app"
+tier="backend"
+kubernetes.io/config.seen="2020-01-02T03:04:05.000000000Z"
+
+invalid line
+escaped="a\"b"
+`)
+	a.Equal(map[string]interface{}{
+		"app":                       "my-app",
+		"tier":                      "backend",
+		"kubernetes.io/config.seen": "2020-01-02T03:04:05.000000000Z",
+		"escaped":                   `a"b`,
+	}, result)
+}
+
+func TestKubernetesEnricher(t *testing.T) {
+	a := assert.New(t)
+
+	dir := t.TempDir()
+	writeFile := func(name, content string) {
+		a.NoError(os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600))
+	}
+	writeFile("namespace", "file-namespace\n")
+	writeFile("uid", "pod-uid\n")
+	writeFile("labels", `app="my-app"`)
+	t.Setenv(EnvPodName, "my-pod")
+	t.Setenv(EnvPodNamespace, "")
+	t.Setenv(EnvPodUID, "")
+	t.Setenv(EnvPodIP, "")
+	t.Setenv(EnvNodeName, "node-1")
+	t.Setenv(EnvContainerName, "")
+
+	e := NewKubernetesEnricher(KubernetesConfig{
+		PodInfoDir: dir,
+	})
+	defer e.Close()
+
+	a.Equal(map[string]interface{}{
+		"pod_name":       "my-pod",
+		"namespace_name": "file-namespace",
+		"pod_id":         "pod-uid",
+		"host":           "node-1",
+		"labels":         map[string]interface{}{"app": "my-app"},
+	}, e.Metadata())
+	a.False(e.reloadIfModified())
+
+	// labels and annotations are reloaded on change.
+	// set a future mtime, so the change is detected on filesystems with coarse mtime.
+	future := time.Now().Add(time.Hour)
+	writeFile("labels", `app="new-app"`)
+	writeFile("annotations", `owner="team-a"`)
+	a.NoError(os.Chtimes(filepath.Join(dir, "labels"), future, future))
+	a.True(e.reloadIfModified())
+	m := e.Metadata()
+	a.Equal(map[string]interface{}{"app": "new-app"}, m["labels"])
+	a.Equal(map[string]interface{}{"owner": "team-a"}, m["annotations"])
+	a.False(e.reloadIfModified())
+
+	hook := NewHook(testHOST, -1)
+	hook.SetKubernetesEnricher(e)
+
+	entry := logrus.NewEntry(logrus.New())
+	entry.Data = logrus.Fields{"tag": fieldTag}
+	_, data := hook.getTagAndData(entry)
+	m, ok := data[KubernetesKey].(map[string]interface{})
+	a.True(ok)
+	a.Equal("my-pod", m["pod_name"])
+
+	a.NoError(hook.Close())
+}
+
+func TestKubernetesEnricherWatch(t *testing.T) {
+	a := assert.New(t)
+
+	dir := t.TempDir()
+	labels := filepath.Join(dir, "labels")
+	a.NoError(os.WriteFile(labels, []byte(`app="my-app"`), 0o600))
+	t.Setenv(EnvPodName, "")
+
+	e := NewKubernetesEnricher(KubernetesConfig{PodInfoDir: dir})
+	tick := make(chan time.Time)
+	done := make(chan struct{})
+	go func() {
+		e.watch(tick)
+		close(done)
+	}()
+
+	future := time.Now().Add(time.Hour)
+	a.NoError(os.WriteFile(labels, []byte(`app="new-app"`), 0o600))
+	a.NoError(os.Chtimes(labels, future, future))
+
+	// the second tick is received after the reload by the first tick is finished.
+	tick <- future
+	tick <- future
+	a.Equal(map[string]interface{}{"app": "new-app"}, e.Metadata()["labels"])
+
+	// Close stops watching.
+	e.Close()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("watch is not stopped by Close")
+	}
+	e.Close() // can be called twice
+}
@@ -0,0 +1,66 @@
+package logrus_fluent
+
+import (
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// LevelEncoder converts log level to the value of level field.
+type LevelEncoder func(logrus.Level) interface{}
+
+// LevelEncoderLowercase encodes log level to lowercase name. (e.g. "warning")
+// This is default encoder.
+func LevelEncoderLowercase(l logrus.Level) interface{} {
+	return l.String()
+}
+
+// LevelEncoderUppercase encodes log level to uppercase name. (e.g. "WARNING")
+func LevelEncoderUppercase(l logrus.Level) interface{} {
+	return strings.ToUpper(l.String())
+}
+
+// LevelEncoderNumeric encodes log level to logrus numeric value. (e.g. 3)
+func LevelEncoderNumeric(l logrus.Level) interface{} {
+	return uint32(l)
+}
+
+// LevelEncoderSyslog encodes log level to syslog severity. (e.g. 4)
+// see https://tools.ietf.org/html/rfc5424#section-6.2.1
+func LevelEncoderSyslog(l logrus.Level) interface{} {
+	switch l {
+	case logrus.PanicLevel:
+		return 0 // Emergency
+	case logrus.FatalLevel:
+		return 2 // Critical
+	case logrus.ErrorLevel:
+		return 3 // Error
+	case logrus.WarnLevel:
+		return 4 // Warning
+	case logrus.InfoLevel:
+		return 6 // Informational
+	default:
+		return 7 // Debug
+	}
+}
+
+// LevelEncoderOpenTelemetry encodes log level to OpenTelemetry SeverityNumber. (e.g. 13)
+// see https://opentelemetry.io/docs/specs/otel/logs/data-model/#field-severitynumber
+func LevelEncoderOpenTelemetry(l logrus.Level) interface{} {
+	switch l {
+	case logrus.PanicLevel:
+		return 24 // FATAL4
+	case logrus.FatalLevel:
+		return 21 // FATAL
+	case logrus.ErrorLevel:
+		return 17 // ERROR
+	case logrus.WarnLevel:
+		return 13 // WARN
+	case logrus.InfoLevel:
+		return 9 // INFO
+	case logrus.DebugLevel:
+		return 5 // DEBUG
+	default:
+		return 1 // TRACE
+	}
+}
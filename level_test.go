@@ -0,0 +1,66 @@
+package logrus_fluent
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLevelEncoders(t *testing.T) {
+	a := assert.New(t)
+
+	tests := []struct {
+		level     logrus.Level
+		lowercase string
+		uppercase string
+		numeric   uint32
+		syslog    int
+		otel      int
+	}{
+		{logrus.PanicLevel, "panic", "PANIC", 0, 0, 24},
+		{logrus.FatalLevel, "fatal", "FATAL", 1, 2, 21},
+		{logrus.ErrorLevel, "error", "ERROR", 2, 3, 17},
+		{logrus.WarnLevel, "warning", "WARNING", 3, 4, 13},
+		{logrus.InfoLevel, "info", "INFO", 4, 6, 9},
+		{logrus.DebugLevel, "debug", "DEBUG", 5, 7, 5},
+		{logrus.TraceLevel, "trace", "TRACE", 6, 7, 1},
+	}
+
+	for _, tt := range tests {
+		a.Equal(tt.lowercase, LevelEncoderLowercase(tt.level))
+		a.Equal(tt.uppercase, LevelEncoderUppercase(tt.level))
+		a.Equal(tt.numeric, LevelEncoderNumeric(tt.level))
+		a.Equal(tt.syslog, LevelEncoderSyslog(tt.level))
+		a.Equal(tt.otel, LevelEncoderOpenTelemetry(tt.level))
+	}
+}
+
+func TestSetLevelEncoder(t *testing.T) {
+	a := assert.New(t)
+
+	hook := NewHook(testHOST, -1)
+	entry := logrus.NewEntry(logrus.New())
+	entry.Level = logrus.WarnLevel
+
+	_, data := hook.getTagAndData(entry)
+	a.Equal("warning", data[LevelField])
+
+	hook.SetLevelEncoder(LevelEncoderSyslog)
+	_, data = hook.getTagAndData(entry)
+	a.Equal(4, data[LevelField])
+
+	hook.SetLevelEncoder(func(l logrus.Level) interface{} {
+		return "custom-" + l.String()
+	})
+	_, data = hook.getTagAndData(entry)
+	a.Equal("custom-warning", data[LevelField])
+
+	hook, err := NewWithConfig(Config{
+		DisableConnectionPool: true,
+		DisableLevelField:     true,
+	})
+	a.NoError(err)
+	_, data = hook.getTagAndData(entry)
+	a.NotContains(data, LevelField)
+}
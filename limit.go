@@ -0,0 +1,91 @@
+package logrus_fluent
+
+import (
+	"fmt"
+	"reflect"
+)
+
+const defaultMaxDepth = 32
+
+// markers used in place of the values which are not converted.
+const (
+	CycleMarker        = "<cycle>"
+	MaxDepthMarker     = "<max depth>"
+	TruncatedMarkerKey = "<truncated>"
+)
+
+// truncatedMarker returns a marker for omitted items in a map or a slice.
+func truncatedMarker(n int) string {
+	return fmt.Sprintf("<truncated %d items>", n)
+}
+
+// convertState holds the state while converting a value.
+type convertState struct {
+	tagNames string // comma separated tag names.
+	depth    int
+	visited  map[visitKey]struct{}
+}
+
+// visitKey identifies a pointer, a map or a slice by pointer identity.
+type visitKey struct {
+	typ reflect.Type
+	ptr uintptr
+	len int
+}
+
+// getVisitKey returns visitKey for a non-nil pointer, map or slice.
+func getVisitKey(rv reflect.Value) (visitKey, bool) {
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Map:
+		if rv.IsNil() {
+			return visitKey{}, false
+		}
+		return visitKey{typ: rv.Type(), ptr: rv.Pointer()}, true
+	case reflect.Slice:
+		if rv.IsNil() {
+			return visitKey{}, false
+		}
+		return visitKey{typ: rv.Type(), ptr: rv.Pointer(), len: rv.Len()}, true
+	}
+	return visitKey{}, false
+}
+
+// visit marks the value as visited, and returns false if it's already visited. (cycle)
+func (st *convertState) visit(key visitKey) bool {
+	if st.visited == nil {
+		st.visited = make(map[visitKey]struct{})
+	}
+	if _, ok := st.visited[key]; ok {
+		return false
+	}
+	st.visited[key] = struct{}{}
+	return true
+}
+
+// leave removes the value from visited values.
+func (st *convertState) leave(key visitKey) {
+	delete(st.visited, key)
+}
+
+// maxDepth returns max depth of nested values.
+func (c Converter) maxDepth() int {
+	if c.MaxDepth > 0 {
+		return c.MaxDepth
+	}
+	return defaultMaxDepth
+}
+
+// maxCollectionSize returns max number of items in the map or the slice being converted.
+// The top level value (e.g. the record) is not truncated, not to drop the hook's own fields.
+func (c Converter) maxCollectionSize(st *convertState) int {
+	if st == nil || st.depth <= 1 {
+		return 0
+	}
+	return c.MaxCollectionSize
+}
+
+// SetConvertLimits sets max depth of nested values and max number of items in a nested map or a slice.
+func (hook *FluentHook) SetConvertLimits(maxDepth, maxCollectionSize int) {
+	hook.converter.MaxDepth = maxDepth
+	hook.converter.MaxCollectionSize = maxCollectionSize
+}
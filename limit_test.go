@@ -0,0 +1,142 @@
+package logrus_fluent
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type node struct {
+	Name string
+	Next *node
+}
+
+type selfEmbedded struct {
+	*selfEmbedded
+	Name string
+}
+
+func TestConvertToValueCycle(t *testing.T) {
+	a := assert.New(t)
+
+	// linked list
+	n1 := &node{Name: "n1"}
+	n2 := &node{Name: "n2", Next: n1}
+	n1.Next = n2
+	a.Equal(map[string]interface{}{
+		"Name": "n1",
+		"Next": map[string]interface{}{
+			"Name": "n2",
+			"Next": CycleMarker,
+		},
+	}, ConvertToValue(n1, TagName))
+
+	// map
+	m := map[string]interface{}{"name": "m"}
+	m["self"] = m
+	a.Equal(map[string]interface{}{
+		"name": "m",
+		"self": CycleMarker,
+	}, ConvertToValue(m, TagName))
+
+	// slice
+	s := []interface{}{"s", nil}
+	s[1] = s
+	a.Equal([]interface{}{"s", CycleMarker}, ConvertToValue(s, TagName))
+
+	// embedded
+	e := &selfEmbedded{Name: "e"}
+	e.selfEmbedded = e
+	a.Equal(map[string]interface{}{"Name": "e"}, ConvertToValue(e, TagName))
+
+	// shared pointer is not a cycle
+	shared := &node{Name: "shared"}
+	a.Equal([]interface{}{
+		map[string]interface{}{"Name": "shared", "Next": nil},
+		map[string]interface{}{"Name": "shared", "Next": nil},
+	}, ConvertToValue([]*node{shared, shared}, TagName))
+}
+
+func TestConvertToValueMaxDepth(t *testing.T) {
+	a := assert.New(t)
+
+	var head *node
+	for i := 0; i < 100; i++ {
+		head = &node{Name: "n", Next: head}
+	}
+
+	c := Converter{TagName: TagName, MaxDepth: 2}
+	a.Equal(map[string]interface{}{
+		"Name": "n",
+		"Next": map[string]interface{}{
+			"Name": "n",
+			"Next": MaxDepthMarker,
+		},
+	}, c.ConvertToValue(head))
+
+	// default max depth
+	v := ConvertToValue(head, TagName)
+	for i := 0; i < defaultMaxDepth; i++ {
+		v = v.(map[string]interface{})["Next"]
+	}
+	a.Equal(MaxDepthMarker, v)
+}
+
+func TestConvertToValueMaxCollectionSize(t *testing.T) {
+	a := assert.New(t)
+
+	c := Converter{TagName: TagName, MaxCollectionSize: 3}
+
+	list := make([]int, 5000)
+	result := c.ConvertToValue([]interface{}{list}).([]interface{})[0].([]interface{})
+	a.Len(result, 4)
+	a.Equal("<truncated 4997 items>", result[3])
+
+	m := map[int]int{1: 1, 2: 2, 3: 3, 4: 4, 5: 5}
+	mresult := c.ConvertToValue(map[string]interface{}{"m": m}).(map[string]interface{})["m"].(map[string]interface{})
+	a.Len(mresult, 4)
+	a.Equal("<truncated 2 items>", mresult[TruncatedMarkerKey])
+
+	// the top level value is not truncated.
+	a.Len(c.ConvertToValue(list), 5000)
+	a.Len(c.ConvertToValue(m), 5)
+}
+
+func TestSetConvertLimitsRecord(t *testing.T) {
+	a := assert.New(t)
+
+	hook := NewHook(testHOST, -1)
+	hook.SetConvertLimits(0, 3)
+
+	entry := logrus.NewEntry(logrus.New())
+	entry.Message = entryMessage
+	entry.Data = logrus.Fields{"tag": fieldTag, "a": 1, "b": 2, "c": 3, "d": []int{1, 2, 3, 4, 5}}
+
+	for _, order := range []KeyOrder{KeyOrderNone, KeyOrderSorted} {
+		hook.SetKeyOrder(order)
+		_, data := hook.getTagAndData(entry)
+
+		// map
+		result := hook.encodeData(data).(map[string]interface{})
+		a.Len(result, 6)
+		a.Equal(entryMessage, result[MessageField])
+		a.Equal("panic", result[LevelField])
+		a.Len(result["d"], 4, "nested values are truncated")
+
+		// direct encoding
+		var b []byte
+		var err error
+		if order == KeyOrderSorted {
+			b, err = hook.converter.appendRecord(nil, data, hook.ownFieldKeys())
+		} else {
+			b, err = hook.converter.AppendMsgpack(nil, data)
+		}
+		a.NoError(err)
+		decoded := decodeMsgpack(t, b).(map[string]interface{})
+		a.Len(decoded, 6)
+		a.Equal(entryMessage, decoded[MessageField])
+		a.Equal("panic", decoded[LevelField])
+		a.Len(decoded["d"], 4)
+	}
+}
@@ -0,0 +1,142 @@
+package logrus_fluent
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// Marshaler is an interface checked by Converter before reflection.
+type Marshaler int
+
+const (
+	// MarshalerText uses MarshalText() of encoding.TextMarshaler.
+	MarshalerText Marshaler = iota + 1
+	// MarshalerJSON uses MarshalJSON() of json.Marshaler.
+	MarshalerJSON
+	// MarshalerStringer uses String() of fmt.Stringer.
+	MarshalerStringer
+)
+
+// DefaultMarshalers is used when Converter.Marshalers is nil.
+var DefaultMarshalers = []Marshaler{MarshalerText, MarshalerJSON}
+
+// marshalers returns the marshalers in priority order.
+func (c Converter) marshalers() []Marshaler {
+	if c.Marshalers == nil {
+		return DefaultMarshalers
+	}
+	return c.Marshalers
+}
+
+// marshal converts the value by the first implemented marshaler.
+func (c Converter) marshal(p interface{}) (interface{}, bool) {
+	if isNilPointer(p) {
+		return nil, false
+	}
+
+	for _, m := range c.marshalers() {
+		switch m {
+		case MarshalerText:
+			if v, ok := p.(encoding.TextMarshaler); ok {
+				b, err := v.MarshalText()
+				if err != nil {
+					continue
+				}
+				return string(b), true
+			}
+		case MarshalerJSON:
+			if v, ok := p.(json.Marshaler); ok {
+				b, err := v.MarshalJSON()
+				if err != nil {
+					continue
+				}
+				result, err := decodeJSON(b)
+				if err != nil {
+					continue
+				}
+				return result, true
+			}
+		case MarshalerStringer:
+			if v, ok := p.(fmt.Stringer); ok {
+				return v.String(), true
+			}
+		}
+	}
+	return nil, false
+}
+
+// decodeJSON decodes the output of MarshalJSON().
+// Numbers are decoded to int64 when possible, not to lose the precision of large integers in float64.
+func decodeJSON(b []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var result interface{}
+	if err := dec.Decode(&result); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("invalid data after top-level value")
+	}
+	return jsonNumbers(result)
+}
+
+// jsonNumbers converts json.Number in the decoded value to int64 or float64.
+func jsonNumbers(v interface{}) (interface{}, error) {
+	var err error
+	switch vv := v.(type) {
+	case json.Number:
+		if n, err := vv.Int64(); err == nil {
+			return n, nil
+		}
+		return vv.Float64()
+	case map[string]interface{}:
+		for k, x := range vv {
+			if vv[k], err = jsonNumbers(x); err != nil {
+				return nil, err
+			}
+		}
+	case []interface{}:
+		for i, x := range vv {
+			if vv[i], err = jsonNumbers(x); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return v, nil
+}
+
+// mapKey converts the map key to string.
+func (c Converter) mapKey(key reflect.Value) string {
+	if key.Kind() == reflect.String && key.Type().PkgPath() == "" {
+		return key.String()
+	}
+
+	k := key.Interface()
+	if v, ok := c.marshal(k); ok {
+		if s, ok := v.(string); ok {
+			return s
+		}
+		return fmt.Sprint(v)
+	}
+	return fmt.Sprint(k)
+}
+
+// isNilPointer checks the value is a typed nil pointer or not.
+func isNilPointer(p interface{}) bool {
+	rv := reflect.ValueOf(p)
+	return rv.Kind() == reflect.Ptr && rv.IsNil()
+}
+
+// SetMarshalers sets the marshalers checked before reflection in priority order.
+// No marshaler is used if empty.
+func (hook *FluentHook) SetMarshalers(marshalers ...Marshaler) {
+	if marshalers == nil {
+		marshalers = []Marshaler{}
+	}
+	hook.converter.Marshalers = marshalers
+}
@@ -0,0 +1,114 @@
+package logrus_fluent
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type color int
+
+func (c color) String() string { return [...]string{"red", "green"}[c] }
+
+type textColor int
+
+func (c textColor) MarshalText() ([]byte, error) { return []byte("text-" + color(c).String()), nil }
+func (c textColor) String() string               { return "string-" + color(c).String() }
+
+type money struct {
+	Amount   int
+	Currency string
+}
+
+func (m money) MarshalJSON() ([]byte, error) {
+	return []byte(`{"amount":"` + strings.Repeat("9", m.Amount) + `","currency":"` + m.Currency + `"}`), nil
+}
+
+type jsonID int64
+
+func (id jsonID) MarshalJSON() ([]byte, error) {
+	return []byte(`{"id":` + strconv.FormatInt(int64(id), 10) + `,"score":1.5,"list":[1,-2]}`), nil
+}
+
+type pointerText struct{ v string }
+
+func (p *pointerText) MarshalText() ([]byte, error) { return []byte(p.v), nil }
+
+func TestConverterMarshalers(t *testing.T) {
+	a := assert.New(t)
+
+	var nilText *pointerText
+	data := map[string]interface{}{
+		"ip":       net.ParseIP("127.0.0.1"),
+		"color":    color(1),
+		"text":     textColor(0),
+		"money":    money{Amount: 3, Currency: "JPY"},
+		"pointer":  &pointerText{v: "pointer"},
+		"nil":      nilText,
+		"keyColor": map[textColor]int{textColor(1): 1},
+	}
+
+	tests := []struct {
+		marshalers []Marshaler
+		expected   map[string]interface{}
+	}{
+		{nil, map[string]interface{}{
+			"ip":       "127.0.0.1",
+			"color":    color(1),
+			"text":     "text-red",
+			"money":    map[string]interface{}{"amount": "999", "currency": "JPY"},
+			"pointer":  "pointer",
+			"nil":      nil,
+			"keyColor": map[string]interface{}{"text-green": 1},
+		}},
+		{[]Marshaler{MarshalerStringer, MarshalerText}, map[string]interface{}{
+			"ip":       "127.0.0.1",
+			"color":    "green",
+			"text":     "string-red",
+			"money":    map[string]interface{}{"Amount": 3, "Currency": "JPY"},
+			"pointer":  "pointer",
+			"nil":      nil,
+			"keyColor": map[string]interface{}{"string-green": 1},
+		}},
+		{[]Marshaler{}, map[string]interface{}{
+			"ip":       []byte(net.ParseIP("127.0.0.1")),
+			"color":    color(1),
+			"text":     textColor(0),
+			"money":    map[string]interface{}{"Amount": 3, "Currency": "JPY"},
+			"pointer":  map[string]interface{}{},
+			"nil":      nil,
+			"keyColor": map[string]interface{}{"string-green": 1}, // fmt.Sprint
+		}},
+	}
+
+	for _, tt := range tests {
+		c := Converter{TagName: TagName, Marshalers: tt.marshalers}
+		a.Equal(tt.expected, c.ConvertToValue(data), "%v", tt.marshalers)
+	}
+}
+
+func TestDecodeJSON(t *testing.T) {
+	a := assert.New(t)
+
+	// larger than 2^53, which can't be represented in float64.
+	id := jsonID(9007199254740993)
+	a.Equal(map[string]interface{}{
+		"id":    int64(9007199254740993),
+		"score": 1.5,
+		"list":  []interface{}{int64(1), int64(-2)},
+	}, ConvertToValue(id, TagName))
+
+	v, err := decodeJSON([]byte(`18446744073709551616`))
+	a.NoError(err)
+	a.Equal(float64(18446744073709551616), v)
+
+	_, err = decodeJSON([]byte(`[1e400]`))
+	a.Error(err, "out of range of float64")
+	_, err = decodeJSON([]byte(`{} {}`))
+	a.Error(err)
+	_, err = decodeJSON([]byte(`{`))
+	a.Error(err)
+}
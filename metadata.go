@@ -0,0 +1,123 @@
+package logrus_fluent
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// MetadataKey is default field name for the metadata.
+	MetadataKey = "metadata"
+
+	defaultCgroupPath = "/proc/self/cgroup"
+)
+
+var containerIDPattern = regexp.MustCompile(`[0-9a-f]{64}`)
+
+// MetadataConfig is settings for host, process and container metadata.
+// Each metadata is gathered only once when the hook is created.
+type MetadataConfig struct {
+	Key         string // field name for the metadata. default is "metadata".
+	Hostname    bool
+	PID         bool
+	Binary      bool
+	GoVersion   bool
+	VCSRevision bool   // vcs.revision from the build info.
+	ContainerID bool   // container id from cgroup file.
+	CgroupPath  string // default is "/proc/self/cgroup".
+}
+
+// SetMetadata gathers metadata and sets it into every log.
+func (hook *FluentHook) SetMetadata(conf MetadataConfig) {
+	hook.metadataKey = conf.Key
+	if hook.metadataKey == "" {
+		hook.metadataKey = MetadataKey
+	}
+	hook.metadata = conf.gather()
+}
+
+// gather returns metadata enabled in the config.
+func (c MetadataConfig) gather() map[string]interface{} {
+	m := make(map[string]interface{})
+	if c.Hostname {
+		if v, err := os.Hostname(); err == nil {
+			m["hostname"] = v
+		}
+	}
+	if c.PID {
+		m["pid"] = os.Getpid()
+	}
+	if c.Binary {
+		if v, err := os.Executable(); err == nil {
+			m["binary"] = filepath.Base(v)
+		}
+	}
+	if c.GoVersion {
+		m["go_version"] = runtime.Version()
+	}
+	if c.VCSRevision {
+		if v := getVCSRevision(); v != "" {
+			m["vcs_revision"] = v
+		}
+	}
+	if c.ContainerID {
+		path := c.CgroupPath
+		if path == "" {
+			path = defaultCgroupPath
+		}
+		if v := getContainerID(path); v != "" {
+			m["container_id"] = v
+		}
+	}
+	return m
+}
+
+// getVCSRevision returns vcs.revision from the build info.
+func getVCSRevision() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	for _, s := range info.Settings {
+		if s.Key == "vcs.revision" {
+			return s.Value
+		}
+	}
+	return ""
+}
+
+// getContainerID returns container id from cgroup file.
+//
+//	12:memory:/docker/<id>
+//	0::/system.slice/docker-<id>.scope
+//	0::/kubepods/besteffort/pod<uid>/<id>
+func getContainerID(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	var id string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if matches := containerIDPattern.FindAllString(scanner.Text(), -1); len(matches) != 0 {
+			id = matches[len(matches)-1]
+		}
+	}
+	return id
+}
+
+// setMetadata adds the metadata into data fields.
+func (hook *FluentHook) setMetadata(data logrus.Fields) {
+	if len(hook.metadata) == 0 {
+		return
+	}
+	data[hook.metadataKey] = hook.metadata
+}
@@ -0,0 +1,75 @@
+package logrus_fluent
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+const testContainerID = "3f1a7c9d2b4e6f8091a2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d5e6f708"
+
+func TestGetContainerID(t *testing.T) {
+	a := assert.New(t)
+
+	tests := []struct {
+		content  string
+		expected string
+	}{
+		{"12:memory:/docker/" + testContainerID + "\n", testContainerID},
+		{"0::/system.slice/docker-" + testContainerID + ".scope\n", testContainerID},
+		{"0::/kubepods/besteffort/pod1234/" + testContainerID + "\n", testContainerID},
+		{"0::/\n", ""},
+	}
+
+	dir := t.TempDir()
+	for i, tt := range tests {
+		path := filepath.Join(dir, "cgroup")
+		a.NoError(os.WriteFile(path, []byte(tt.content), 0o600))
+		a.Equal(tt.expected, getContainerID(path), "#%d", i)
+	}
+	a.Equal("", getContainerID(filepath.Join(dir, "not_found")))
+}
+
+func TestSetMetadata(t *testing.T) {
+	a := assert.New(t)
+
+	path := filepath.Join(t.TempDir(), "cgroup")
+	a.NoError(os.WriteFile(path, []byte("0::/docker/"+testContainerID+"\n"), 0o600))
+
+	hook, err := NewWithConfig(Config{
+		DisableConnectionPool: true,
+		Metadata: MetadataConfig{
+			PID:         true,
+			GoVersion:   true,
+			ContainerID: true,
+			CgroupPath:  path,
+		},
+	})
+	a.NoError(err)
+
+	entry := logrus.NewEntry(logrus.New())
+	entry.Data = logrus.Fields{"tag": fieldTag}
+
+	_, data := hook.getTagAndData(entry)
+	m, ok := data[MetadataKey].(map[string]interface{})
+	a.True(ok)
+	a.Equal(os.Getpid(), m["pid"])
+	a.Equal(runtime.Version(), m["go_version"])
+	a.Equal(testContainerID, m["container_id"])
+	a.NotContains(m, "hostname")
+
+	hook.SetMetadata(MetadataConfig{Key: "host", Hostname: true})
+	_, data = hook.getTagAndData(entry)
+	a.NotContains(data, MetadataKey)
+	m, ok = data["host"].(map[string]interface{})
+	a.True(ok)
+	a.Contains(m, "hostname")
+
+	hook.SetMetadata(MetadataConfig{})
+	_, data = hook.getTagAndData(entry)
+	a.NotContains(data, MetadataKey)
+}
@@ -0,0 +1,390 @@
+package logrus_fluent
+
+import (
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/fluent/fluent-logger-golang/fluent"
+	"github.com/sirupsen/logrus"
+	"github.com/tinylib/msgp/msgp"
+)
+
+// maxPooledRecordSize is the max buffer size kept in the pool.
+const maxPooledRecordSize = 64 * 1024
+
+// encodedRecord is a record pre-encoded in msgpack format.
+// fluent-logger appends it to the message without marshaling again.
+type encodedRecord struct {
+	b []byte
+}
+
+// MarshalMsg implements msgp.Marshaler.
+func (r *encodedRecord) MarshalMsg(b []byte) ([]byte, error) {
+	return append(b, r.b...), nil
+}
+
+var encodedRecordPool = sync.Pool{
+	New: func() interface{} { return &encodedRecord{} },
+}
+
+// release puts the record back to the pool.
+func (r *encodedRecord) release() {
+	if cap(r.b) > maxPooledRecordSize {
+		return
+	}
+	r.b = r.b[:0]
+	encodedRecordPool.Put(r)
+}
+
+// AppendMsgpack appends the value to b in msgpack format.
+// The value is converted in the same way as ConvertToValue without building intermediate maps.
+func (c Converter) AppendMsgpack(b []byte, p interface{}) ([]byte, error) {
+	return c.appendValue(b, p, nil)
+}
+
+func (c Converter) appendValue(b []byte, p interface{}, st *convertState) ([]byte, error) {
+	// fast path for common types
+	switch v := p.(type) {
+	case nil:
+		return msgp.AppendNil(b), nil
+	case string:
+		return msgp.AppendString(b, v), nil
+	case int:
+		return msgp.AppendInt(b, v), nil
+	case int64:
+		return msgp.AppendInt64(b, v), nil
+	case bool:
+		return msgp.AppendBool(b, v), nil
+	case float64:
+		return msgp.AppendFloat64(b, v), nil
+	}
+
+	if v, ok := c.marshalFluent(p, st); ok {
+		return c.appendConverted(b, v)
+	}
+	if v, ok := c.encodeTimeValue(p); ok {
+		return c.appendConverted(b, v)
+	}
+
+	rv := toValue(p)
+	if rv.Kind() == reflect.Struct {
+		if err, ok := p.(error); ok {
+			return c.appendConverted(b, c.encodeError(err))
+		}
+	}
+	if v, ok := c.marshal(p); ok {
+		return c.appendConverted(b, v)
+	}
+
+	switch rv.Kind() {
+	case reflect.Struct:
+		// nested value
+	case reflect.Map, reflect.Slice:
+		if rv.IsNil() {
+			return msgp.AppendNil(b), nil
+		}
+		if isBytes(rv) {
+			return c.appendBytes(b, rv)
+		}
+	case reflect.Array:
+		if isBytes(rv) {
+			return c.appendBytes(b, rv)
+		}
+	case reflect.Invalid, reflect.Chan, reflect.Func, reflect.UnsafePointer:
+		return msgp.AppendNil(b), nil
+	case reflect.Ptr:
+		return msgp.AppendString(b, MaxDepthMarker), nil // too many indirections
+	default:
+		return c.appendConverted(b, rv.Interface())
+	}
+
+	if st == nil {
+		st = c.newConvertState()
+	}
+	if st.depth >= c.maxDepth() {
+		return msgp.AppendString(b, MaxDepthMarker), nil
+	}
+	key, ok := getVisitKey(reflect.ValueOf(p))
+	if ok {
+		if !st.visit(key) {
+			return msgp.AppendString(b, CycleMarker), nil
+		}
+		defer st.leave(key)
+	}
+	st.depth++
+	defer func() { st.depth-- }()
+
+	switch rv.Kind() {
+	case reflect.Struct:
+		return c.appendStruct(b, rv, st)
+	case reflect.Map:
+		switch m := p.(type) {
+		case logrus.Fields:
+			return c.appendFields(b, m, st)
+		case map[string]interface{}:
+			return c.appendFields(b, m, st)
+		}
+		return c.appendMap(b, rv, st)
+	default:
+		return c.appendSlice(b, rv, st)
+	}
+}
+
+// appendBytes appends a byte slice or a byte array by BytesEncoding.
+func (c Converter) appendBytes(b []byte, rv reflect.Value) ([]byte, error) {
+	if c.BytesEncoding == BytesBinary && rv.Kind() == reflect.Slice && rv.Type().Elem() == byteType {
+		return msgp.AppendBytes(b, rv.Bytes()), nil
+	}
+	return c.appendConverted(b, c.encodeBytes(rv))
+}
+
+// appendHeader appends the header of a map or an array and returns the number of items to encode.
+func (c Converter) appendHeader(b []byte, size int, isMap bool, st *convertState) ([]byte, int) {
+	n, header := size, size
+	if max := c.maxCollectionSize(st); max > 0 && size > max {
+		n, header = max, max+1 // items and the truncated marker
+	}
+	if isMap {
+		return msgp.AppendMapHeader(b, uint32(header)), n
+	}
+	return msgp.AppendArrayHeader(b, uint32(header)), n
+}
+
+func (c Converter) appendFields(b []byte, m map[string]interface{}, st *convertState) ([]byte, error) {
+	if c.KeyOrder == KeyOrderSorted {
+		return c.appendFieldsInOrder(b, m, sortedKeys(m), st)
+	}
+
+	var n int
+	b, n = c.appendHeader(b, len(m), true, st)
+
+	var err error
+	i := 0
+	for k, v := range m {
+		if i >= n {
+			b = msgp.AppendString(b, TruncatedMarkerKey)
+			return msgp.AppendString(b, truncatedMarker(len(m)-i)), nil
+		}
+		b = msgp.AppendString(b, k)
+		if b, err = c.appendValue(b, v, st); err != nil {
+			return b, err
+		}
+		i++
+	}
+	return b, nil
+}
+
+// appendFieldsInOrder appends the map in the order of the keys.
+func (c Converter) appendFieldsInOrder(b []byte, m map[string]interface{}, keys []string, st *convertState) ([]byte, error) {
+	var n int
+	b, n = c.appendHeader(b, len(keys), true, st)
+
+	var err error
+	for _, k := range keys[:n] {
+		b = msgp.AppendString(b, k)
+		if b, err = c.appendValue(b, m[k], st); err != nil {
+			return b, err
+		}
+	}
+	if n < len(keys) {
+		b = msgp.AppendString(b, TruncatedMarkerKey)
+		b = msgp.AppendString(b, truncatedMarker(len(keys)-n))
+	}
+	return b, nil
+}
+
+func (c Converter) appendMap(b []byte, rv reflect.Value, st *convertState) ([]byte, error) {
+	if c.KeyOrder == KeyOrderSorted {
+		keys, values := c.sortedMapEntries(rv)
+		return c.appendFieldsInOrder(b, values, keys, st)
+	}
+
+	var n int
+	b, n = c.appendHeader(b, rv.Len(), true, st)
+
+	var err error
+	i := 0
+	iter := rv.MapRange()
+	for iter.Next() {
+		if i >= n {
+			b = msgp.AppendString(b, TruncatedMarkerKey)
+			return msgp.AppendString(b, truncatedMarker(rv.Len()-i)), nil
+		}
+		b = msgp.AppendString(b, c.mapKey(iter.Key()))
+		if b, err = c.appendValue(b, iter.Value().Interface(), st); err != nil {
+			return b, err
+		}
+		i++
+	}
+	return b, nil
+}
+
+func (c Converter) appendSlice(b []byte, rv reflect.Value, st *convertState) ([]byte, error) {
+	var n int
+	b, n = c.appendHeader(b, rv.Len(), false, st)
+
+	var err error
+	for i := 0; i < n; i++ {
+		if b, err = c.appendValue(b, rv.Index(i).Interface(), st); err != nil {
+			return b, err
+		}
+	}
+	if n < rv.Len() {
+		b = msgp.AppendString(b, truncatedMarker(rv.Len()-n))
+	}
+	return b, nil
+}
+
+// appendStruct appends the fields of the struct.
+// A struct with embedded structs or duplicated names is converted to a map first to merge the fields.
+func (c Converter) appendStruct(b []byte, rv reflect.Value, st *convertState) ([]byte, error) {
+	enc := getStructEncoder(rv.Type(), st.tagNames)
+	if !enc.flat {
+		return c.appendConverted(b, c.convertFromStruct(rv, st))
+	}
+
+	fields := enc.fields
+	if c.KeyOrder == KeyOrderSorted {
+		fields = enc.sortedFields
+	}
+
+	n := 0
+	for _, f := range fields {
+		if !f.isEmpty(rv.Field(f.index)) {
+			n++
+		}
+	}
+	b = msgp.AppendMapHeader(b, uint32(n))
+
+	var err error
+	for _, f := range fields {
+		v := rv.Field(f.index)
+		if f.isEmpty(v) {
+			continue
+		}
+
+		b = msgp.AppendString(b, f.name)
+		switch {
+		case f.asString:
+			b, err = c.appendConverted(b, toJSONString(v))
+		case f.basic:
+			b = appendBasic(b, v)
+		default:
+			b, err = c.appendValue(b, v.Interface(), st)
+		}
+		if err != nil {
+			return b, err
+		}
+	}
+	return b, nil
+}
+
+// appendConverted appends the value converted by Converter.
+func (c Converter) appendConverted(b []byte, v interface{}) ([]byte, error) {
+	var err error
+	switch v := v.(type) {
+	case map[string]interface{}:
+		b = msgp.AppendMapHeader(b, uint32(len(v)))
+		if c.KeyOrder == KeyOrderSorted {
+			for _, k := range sortedKeys(v) {
+				b = msgp.AppendString(b, k)
+				if b, err = c.appendConverted(b, v[k]); err != nil {
+					return b, err
+				}
+			}
+			return b, nil
+		}
+		for k, x := range v {
+			b = msgp.AppendString(b, k)
+			if b, err = c.appendConverted(b, x); err != nil {
+				return b, err
+			}
+		}
+		return b, nil
+	case []interface{}:
+		b = msgp.AppendArrayHeader(b, uint32(len(v)))
+		for _, x := range v {
+			if b, err = c.appendConverted(b, x); err != nil {
+				return b, err
+			}
+		}
+		return b, nil
+	case msgp.Marshaler, msgp.Extension:
+		return msgp.AppendIntf(b, v)
+	}
+
+	rv := reflect.ValueOf(v)
+	if isBasicKind(rv.Kind()) {
+		return appendBasic(b, rv), nil
+	}
+	return msgp.AppendIntf(b, v)
+}
+
+// isBasicKind checks the kind is bool, number or string.
+func isBasicKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.Complex64, reflect.Complex128,
+		reflect.String:
+		return true
+	}
+	return false
+}
+
+// appendBasic appends the value of basic kind. (see isBasicKind)
+func appendBasic(b []byte, rv reflect.Value) []byte {
+	switch rv.Kind() {
+	case reflect.Bool:
+		return msgp.AppendBool(b, rv.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return msgp.AppendInt64(b, rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return msgp.AppendUint64(b, rv.Uint())
+	case reflect.Float32:
+		return msgp.AppendFloat32(b, float32(rv.Float()))
+	case reflect.Float64:
+		return msgp.AppendFloat64(b, rv.Float())
+	case reflect.Complex64:
+		return msgp.AppendComplex64(b, complex64(rv.Complex()))
+	case reflect.Complex128:
+		return msgp.AppendComplex128(b, rv.Complex())
+	default:
+		return msgp.AppendString(b, rv.String())
+	}
+}
+
+// postEncoded encodes the data into the pooled buffer and posts it.
+func (hook *FluentHook) postEncoded(logger *fluent.Fluent, tag string, t time.Time, data logrus.Fields) error {
+	r := encodedRecordPool.Get().(*encodedRecord)
+	defer r.release()
+
+	var err error
+	if hook.converter.KeyOrder == KeyOrderSorted {
+		r.b, err = hook.converter.appendRecord(r.b[:0], data, hook.ownFieldKeys())
+	} else {
+		r.b, err = hook.converter.AppendMsgpack(r.b[:0], data)
+	}
+	if err != nil {
+		return err
+	}
+	return logger.PostWithTime(tag, t, r)
+}
+
+// useDirectEncoding checks the record can be encoded without intermediate maps.
+// Flatten, OmitEmpty, SizeLimit, Chunk and JSON format need the converted map.
+func (hook *FluentHook) useDirectEncoding(logger *fluent.Fluent) bool {
+	if hook.flatten != nil || hook.omitEmpty != nil || hook.sizeLimit != nil || hook.chunk != nil {
+		return false
+	}
+	return hook.directEncoding && !logger.MarshalAsJSON
+}
+
+// SetDirectEncoding sets the records are encoded into msgpack directly without intermediate maps.
+// It's not used when Flatten, OmitEmpty, SizeLimit, Chunk or MarshalAsJSON is set.
+func (hook *FluentHook) SetDirectEncoding(enabled bool) {
+	hook.directEncoding = enabled
+}
@@ -0,0 +1,195 @@
+package logrus_fluent
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/fluent/fluent-logger-golang/fluent"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/tinylib/msgp/msgp"
+)
+
+// decodeMsgpack decodes msgpack bytes to compare the encoded values.
+func decodeMsgpack(t *testing.T, b []byte) interface{} {
+	v, rest, err := msgp.ReadIntfBytes(b)
+	assert.NoError(t, err)
+	assert.Empty(t, rest)
+	return v
+}
+
+func TestAppendMsgpack(t *testing.T) {
+	a := assert.New(t)
+
+	type node struct {
+		Name string `fluent:"name"`
+		Next *node  `fluent:"next"`
+	}
+	cycle := &node{Name: "a"}
+	cycle.Next = cycle
+
+	now := time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC)
+	values := []interface{}{
+		nil,
+		"str",
+		1,
+		int8(-2),
+		uint16(3),
+		int64(-4),
+		float32(1.5),
+		2.5,
+		true,
+		[]byte("bytes"),
+		[4]byte{1, 2, 3, 4},
+		[]string{},
+		[]int{1, 2, 3},
+		map[int]string{1: "one"},
+		map[string]interface{}{"nested": map[string]interface{}{"a": []interface{}{1, "b"}}},
+		logrus.Fields{"time": now, "duration": time.Second},
+		logrus.Fields{"error": errors.New("err")},
+		now,
+		&now,
+		time.Second,
+		net.ParseIP("127.0.0.1"),
+		Creature{Name: "cat", animal: &animal{Fur: true}},
+		&benchRequest{ID: "id", Status: 200, Headers: map[string]string{"a": "b"}, benchUser: &benchUser{UserID: 1}},
+		jsonOwner{ID: 1, Name: "owner", Pets: []jsonPet{{Name: "pet"}}},
+		bigRequest{ID: 1, Route: "/users"},
+		ptrSummary{ID: 2},
+		(*Creature)(nil),
+		cycle,
+	}
+
+	converters := []Converter{
+		{TagName: TagName},
+		{TagNames: []string{"fluent", "json"}},
+		{TagName: TagName, BytesEncoding: BytesHex, TimeEncoding: TimeUnix, DurationEncoding: DurationString},
+		{TagName: TagName, KeyOrder: KeyOrderSorted},
+		{TagName: TagName, MaxDepth: 2, MaxCollectionSize: 2}, // truncated maps are not compared because of the random order.
+	}
+	for _, c := range converters {
+		for _, v := range values {
+			expected, err := msgp.AppendIntf(nil, c.ConvertToValue(v))
+			a.NoError(err)
+
+			b, err := c.AppendMsgpack(nil, v)
+			a.NoError(err)
+			a.Equal(decodeMsgpack(t, expected), decodeMsgpack(t, b), "%#v", v)
+		}
+	}
+
+	// named basic type is encoded by the kind.
+	b, err := Converter{Marshalers: []Marshaler{}}.AppendMsgpack(nil, map[string]interface{}{"color": color(1)})
+	a.NoError(err)
+	a.Equal(map[string]interface{}{"color": int64(1)}, decodeMsgpack(t, b))
+}
+
+func TestPostEncoded(t *testing.T) {
+	a := assert.New(t)
+
+	hook := NewHook(testHOST, -1)
+	hook.SetDirectEncoding(true)
+	entry := logrus.NewEntry(logrus.New())
+	entry.Message = "message"
+	entry.Level = logrus.InfoLevel
+	entry.Data = logrus.Fields{"tag": fieldTag, "value": "data", "list": []int{1, 2}}
+
+	_, data := hook.getTagAndData(entry)
+	r := &encodedRecord{}
+	r.b, _ = hook.converter.AppendMsgpack(nil, data)
+
+	// the record is embedded into the forward protocol message as it is.
+	encode := func(record interface{}) interface{} {
+		msg := fluent.Message{Tag: fieldTag, Time: 1, Record: record}
+		b, err := msg.MarshalMsg(nil)
+		a.NoError(err)
+
+		var decoded fluent.Message
+		_, err = decoded.UnmarshalMsg(b)
+		a.NoError(err)
+		return decoded.Record
+	}
+	a.Equal(encode(hook.encodeData(data)), encode(r))
+}
+
+func TestSetDirectEncoding(t *testing.T) {
+	a := assert.New(t)
+
+	hook := NewHook(testHOST, -1)
+	logger := &fluent.Fluent{}
+	a.False(hook.useDirectEncoding(logger))
+
+	hook.SetDirectEncoding(true)
+	a.True(hook.useDirectEncoding(logger))
+
+	// JSON format and flatten need the converted map.
+	logger.MarshalAsJSON = true
+	a.False(hook.useDirectEncoding(logger))
+	logger.MarshalAsJSON = false
+
+	hook.SetFlatten(&FlattenConfig{})
+	a.False(hook.useDirectEncoding(logger))
+	hook.SetFlatten(nil)
+
+	hook.SetOmitEmpty(&OmitEmptyConfig{})
+	a.False(hook.useDirectEncoding(logger))
+	hook.SetOmitEmpty(nil)
+
+	hook.SetSizeLimit(&SizeLimitConfig{})
+	a.False(hook.useDirectEncoding(logger))
+	hook.SetSizeLimit(nil)
+
+	hook.SetChunk(&ChunkConfig{})
+	a.False(hook.useDirectEncoding(logger))
+}
+
+func newBenchEntry() *logrus.Entry {
+	entry := logrus.NewEntry(logrus.New())
+	entry.Message = "request finished"
+	entry.Level = logrus.InfoLevel
+	entry.Time = time.Now()
+	entry.Data = logrus.Fields{
+		"tag":       fieldTag,
+		"method":    "GET",
+		"path":      "/api/v1/items",
+		"status":    200,
+		"latency":   0.025,
+		"cached":    true,
+		"user_id":   int64(12345),
+		"tags":      []string{"a", "b"},
+		"headers":   map[string]string{"Accept": "*/*"},
+		"request":   &benchRequest{ID: "req-1", Method: "GET", Status: 200},
+		"timestamp": time.Now(),
+	}
+	return entry
+}
+
+func BenchmarkEncodeRecord(b *testing.B) {
+	hook := NewHook(testHOST, -1)
+	entry := newBenchEntry()
+
+	b.Run("map", func(b *testing.B) {
+		buf := make([]byte, 0, 1024)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_, data := hook.getTagAndData(entry)
+			msg := fluent.Message{Tag: fieldTag, Time: entry.Time.Unix(), Record: hook.encodeData(data)}
+			buf, _ = msg.MarshalMsg(buf[:0])
+		}
+	})
+
+	b.Run("direct", func(b *testing.B) {
+		buf := make([]byte, 0, 1024)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_, data := hook.getTagAndData(entry)
+			r := encodedRecordPool.Get().(*encodedRecord)
+			r.b, _ = hook.converter.AppendMsgpack(r.b[:0], data)
+			msg := fluent.Message{Tag: fieldTag, Time: entry.Time.Unix(), Record: r}
+			buf, _ = msg.MarshalMsg(buf[:0])
+			r.release()
+		}
+	})
+}
@@ -0,0 +1,109 @@
+package logrus_fluent
+
+import "reflect"
+
+// OmitEmptyConfig is settings for dropping empty values from the converted record.
+//
+//	{"user": {"id": 1, "name": ""}, "error": nil, "tags": []}
+//	=> {"user": {"id": 1}}
+type OmitEmptyConfig struct {
+	KeepZero bool     // zero numbers and false are kept if true. nil and empty strings, maps and slices are dropped.
+	Keep     []string // dotted paths of the fields kept even if empty. (e.g. "status", "user.name")
+}
+
+// OmitEmpty drops the empty values from the data and the nested maps.
+// The items of slices are not dropped, because the index is meaningful, but the maps in them are.
+// The maps which become empty are dropped too.
+func (c OmitEmptyConfig) OmitEmpty(data map[string]interface{}) map[string]interface{} {
+	return c.omitEmpty(data, nil)
+}
+
+// omitEmpty drops the empty values except the top-level fields in protected. (e.g. level 0 and empty message)
+func (c OmitEmptyConfig) omitEmpty(data map[string]interface{}, protected []string) map[string]interface{} {
+	var keep map[string]struct{}
+	if n := len(c.Keep) + len(protected); n != 0 {
+		keep = make(map[string]struct{}, n)
+		for _, k := range c.Keep {
+			keep[k] = struct{}{}
+		}
+		for _, k := range protected {
+			if k != "" {
+				keep[k] = struct{}{}
+			}
+		}
+	}
+	return c.omitMap(data, "", keep)
+}
+
+func (c OmitEmptyConfig) omitMap(m map[string]interface{}, path string, keep map[string]struct{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		p := k
+		if path != "" {
+			p = path + "." + k
+		}
+		v = c.omitValue(v, p, keep)
+		if _, ok := keep[p]; !ok && c.isEmpty(v) {
+			continue
+		}
+		result[k] = v
+	}
+	return result
+}
+
+func (c OmitEmptyConfig) omitValue(v interface{}, path string, keep map[string]struct{}) interface{} {
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		if vv == nil {
+			return vv
+		}
+		return c.omitMap(vv, path, keep)
+	case []interface{}:
+		if vv == nil {
+			return vv
+		}
+		result := make([]interface{}, len(vv))
+		for i, item := range vv {
+			result[i] = c.omitValue(item, path, keep)
+		}
+		return result
+	default:
+		return v
+	}
+}
+
+// isEmpty checks the converted value is empty.
+func (c OmitEmptyConfig) isEmpty(v interface{}) bool {
+	switch vv := v.(type) {
+	case nil:
+		return true
+	case string:
+		return vv == ""
+	case map[string]interface{}:
+		return len(vv) == 0
+	case []interface{}:
+		return len(vv) == 0
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.String, reflect.Map, reflect.Slice, reflect.Array:
+		return rv.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return rv.IsNil()
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.Complex64, reflect.Complex128:
+		return !c.KeepZero && rv.IsZero()
+	}
+	return false
+}
+
+// SetOmitEmpty sets settings for dropping empty values from the record.
+// The hook's own fields (level, message, time, etc.) are never dropped.
+// Dropping is disabled when conf is nil.
+func (hook *FluentHook) SetOmitEmpty(conf *OmitEmptyConfig) {
+	hook.omitEmpty = conf
+}
@@ -0,0 +1,153 @@
+package logrus_fluent
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOmitEmpty(t *testing.T) {
+	a := assert.New(t)
+
+	newData := func() map[string]interface{} {
+		return map[string]interface{}{
+			"value":  fieldValue,
+			"empty":  "",
+			"nil":    nil,
+			"zero":   0,
+			"false":  false,
+			"color":  color(0),
+			"bytes":  []byte{},
+			"status": "",
+			"user": map[string]interface{}{
+				"id":   1,
+				"name": "",
+				"profile": map[string]interface{}{
+					"bio": "",
+				},
+				"items": []interface{}{
+					map[string]interface{}{"id": 0, "name": "item"},
+					nil,
+					"",
+				},
+			},
+			"list": []interface{}{},
+			"map":  map[string]interface{}{},
+		}
+	}
+
+	tests := []struct {
+		conf     OmitEmptyConfig
+		expected map[string]interface{}
+	}{
+		{
+			conf: OmitEmptyConfig{},
+			expected: map[string]interface{}{
+				"value": fieldValue,
+				"user": map[string]interface{}{
+					"id": 1,
+					"items": []interface{}{
+						map[string]interface{}{"name": "item"},
+						nil,
+						"",
+					},
+				},
+			},
+		},
+		{
+			conf: OmitEmptyConfig{KeepZero: true, Keep: []string{"status", "user.profile.bio"}},
+			expected: map[string]interface{}{
+				"value":  fieldValue,
+				"zero":   0,
+				"false":  false,
+				"color":  color(0),
+				"status": "",
+				"user": map[string]interface{}{
+					"id": 1,
+					"profile": map[string]interface{}{
+						"bio": "",
+					},
+					"items": []interface{}{
+						map[string]interface{}{"id": 0, "name": "item"},
+						nil,
+						"",
+					},
+				},
+			},
+		},
+		{
+			conf: OmitEmptyConfig{Keep: []string{"user.profile", "list"}},
+			expected: map[string]interface{}{
+				"value": fieldValue,
+				"user": map[string]interface{}{
+					"id":      1,
+					"profile": map[string]interface{}{},
+					"items": []interface{}{
+						map[string]interface{}{"name": "item"},
+						nil,
+						"",
+					},
+				},
+				"list": []interface{}{},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		data := newData()
+		a.Equal(tt.expected, tt.conf.OmitEmpty(data), "%#v", tt.conf)
+		a.Equal(newData(), data, "the original data is not modified")
+	}
+}
+
+func TestSetOmitEmpty(t *testing.T) {
+	a := assert.New(t)
+
+	hook := NewHook(testHOST, -1)
+	hook.SetOmitEmpty(&OmitEmptyConfig{})
+	hook.SetFlatten(&FlattenConfig{})
+
+	var nilErr error
+	entry := logrus.NewEntry(logrus.New())
+	entry.Data = logrus.Fields{
+		"tag":      fieldTag,
+		"user":     "",
+		"error":    nilErr,
+		"err":      errors.New("err"),
+		"creature": &Creature{Name: "cat"},
+		"headers":  map[string]string{},
+	}
+	entry.Message = entryMessage
+
+	_, data := hook.getTagAndData(entry)
+	result, ok := hook.encodeData(data).(map[string]interface{})
+	a.True(ok)
+	a.Equal(map[string]interface{}{
+		MessageField:    entryMessage,
+		LevelField:      "panic",
+		"err":           "err",
+		"creature.Name": "cat",
+	}, result)
+
+	// the hook's own fields are not dropped even if empty.
+	hook.SetLevelEncoder(LevelEncoderNumeric)
+	entry.Message = ""
+	_, data = hook.getTagAndData(entry)
+	result, ok = hook.encodeData(data).(map[string]interface{})
+	a.True(ok)
+	a.Equal(map[string]interface{}{
+		MessageField:    "",
+		LevelField:      uint32(logrus.PanicLevel),
+		"err":           "err",
+		"creature.Name": "cat",
+	}, result)
+
+	hook.SetOmitEmpty(nil)
+	_, data = hook.getTagAndData(entry)
+	result, ok = hook.encodeData(data).(map[string]interface{})
+	a.True(ok)
+	a.Contains(result, "user")
+	a.Contains(result, "error")
+}
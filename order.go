@@ -0,0 +1,162 @@
+package logrus_fluent
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+
+	"github.com/tinylib/msgp/msgp"
+)
+
+// KeyOrder is the order of keys in the encoded record.
+type KeyOrder int
+
+// Key orders.
+// Go maps don't keep the insertion order, so the fields of the entry are sorted by name.
+const (
+	KeyOrderNone   KeyOrder = iota // keys are encoded in the map iteration order. (random)
+	KeyOrderSorted                 // the hook's own fields first, and then the other keys sorted by name at every level.
+)
+
+// orderedRecord is a record encoded in the order of the keys.
+// It's posted instead of the map, so the same entry is always encoded into the same bytes.
+type orderedRecord struct {
+	converter Converter
+	keys      []string
+	data      map[string]interface{}
+}
+
+// newOrderedRecord returns orderedRecord which has firstKeys first and the other keys sorted.
+func newOrderedRecord(c Converter, data map[string]interface{}, firstKeys []string) *orderedRecord {
+	return &orderedRecord{
+		converter: c,
+		keys:      orderedKeys(data, firstKeys),
+		data:      data,
+	}
+}
+
+// MarshalMsg implements msgp.Marshaler.
+func (r *orderedRecord) MarshalMsg(b []byte) ([]byte, error) {
+	b = msgp.AppendMapHeader(b, uint32(len(r.keys)))
+
+	var err error
+	for _, k := range r.keys {
+		b = msgp.AppendString(b, k)
+		if b, err = r.converter.appendConverted(b, r.data[k]); err != nil {
+			return b, err
+		}
+	}
+	return b, nil
+}
+
+// MarshalJSON implements json.Marshaler.
+// encoding/json sorts the keys of nested maps.
+func (r *orderedRecord) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, k := range r.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(k)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(r.data[k])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// orderedKeys returns firstKeys existing in data, and then the other keys sorted.
+func orderedKeys(data map[string]interface{}, firstKeys []string) []string {
+	keys := make([]string, 0, len(data))
+	seen := make(map[string]struct{}, len(firstKeys))
+	for _, k := range firstKeys {
+		if _, ok := data[k]; !ok || k == "" {
+			continue
+		}
+		if _, ok := seen[k]; ok {
+			continue
+		}
+		seen[k] = struct{}{}
+		keys = append(keys, k)
+	}
+	rest := len(keys)
+	for k := range data {
+		if _, ok := seen[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys[rest:])
+	return keys
+}
+
+// sortedKeys returns the keys of the map sorted.
+func sortedKeys(m map[string]interface{}) []string {
+	return orderedKeys(m, nil)
+}
+
+// sortedMapEntries returns the sorted keys and the values of the map.
+// The keys are converted to string in the same way as ConvertToValue.
+func (c Converter) sortedMapEntries(rv reflect.Value) ([]string, map[string]interface{}) {
+	values := make(map[string]interface{}, rv.Len())
+	iter := rv.MapRange()
+	for iter.Next() {
+		values[c.mapKey(iter.Key())] = iter.Value().Interface()
+	}
+	return sortedKeys(values), values
+}
+
+// appendRecord appends the top level fields in the order of orderedKeys.
+// The fields are in the same depth as AppendMsgpack, which counts the record as the first level.
+func (c Converter) appendRecord(b []byte, data map[string]interface{}, firstKeys []string) ([]byte, error) {
+	st := c.newConvertState()
+	st.depth = 1
+	return c.appendFieldsInOrder(b, data, orderedKeys(data, firstKeys), st)
+}
+
+// ownFieldKeys returns the field names set by the hook in the order of the record.
+func (hook *FluentHook) ownFieldKeys() []string {
+	keys := []string{hook.levelField, hook.messageField, hook.timeField}
+	if c := hook.caller; c != nil {
+		keys = append(keys, c.FileKey, c.LineKey, c.FunctionKey)
+	}
+	if c := hook.trace; c != nil {
+		keys = append(keys, c.TraceIDKey, c.SpanIDKey, c.TraceFlagsKey)
+	}
+	if len(hook.metadata) != 0 {
+		keys = append(keys, hook.metadataKey)
+	}
+	if hook.kubernetes != nil {
+		keys = append(keys, KubernetesKey)
+	}
+	if hook.chunk != nil {
+		keys = append(keys, ChunkIDField, ChunkIndexField, ChunkTotalField, ChunkFieldsField)
+	}
+	return keys
+}
+
+// orderRecord wraps the converted record to encode the keys in order.
+func (hook *FluentHook) orderRecord(record interface{}) interface{} {
+	if hook.converter.KeyOrder != KeyOrderSorted {
+		return record
+	}
+	m, ok := record.(map[string]interface{})
+	if !ok {
+		return record
+	}
+	return newOrderedRecord(hook.converter, m, hook.ownFieldKeys())
+}
+
+// SetKeyOrder sets the order of keys in the encoded record.
+func (hook *FluentHook) SetKeyOrder(order KeyOrder) {
+	hook.converter.KeyOrder = order
+}
@@ -0,0 +1,157 @@
+package logrus_fluent
+
+import (
+	"encoding/json"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/fluent/fluent-logger-golang/fluent"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/tinylib/msgp/msgp"
+)
+
+// readMapKeys reads the keys of the top level map in msgpack bytes.
+func readMapKeys(t *testing.T, b []byte) []string {
+	size, b, err := msgp.ReadMapHeaderBytes(b)
+	assert.NoError(t, err)
+
+	keys := make([]string, 0, size)
+	for i := uint32(0); i < size; i++ {
+		var k string
+		k, b, err = msgp.ReadStringBytes(b)
+		assert.NoError(t, err)
+		keys = append(keys, k)
+		b, err = msgp.Skip(b)
+		assert.NoError(t, err)
+	}
+	return keys
+}
+
+func TestOrderedKeys(t *testing.T) {
+	a := assert.New(t)
+
+	data := map[string]interface{}{"b": 1, "level": 2, "a": 3, "message": 4}
+	a.Equal([]string{"level", "message", "a", "b"}, orderedKeys(data, []string{"level", "message", "", "time", "level"}))
+	a.Equal([]string{"a", "b", "level", "message"}, sortedKeys(data))
+	a.Equal([]string{}, sortedKeys(nil))
+}
+
+func TestKeyOrder(t *testing.T) {
+	a := assert.New(t)
+
+	hook := NewHook(testHOST, -1)
+	hook.SetKeyOrder(KeyOrderSorted)
+	hook.SetTimeField("time")
+	hook.SetCaller(&CallerConfig{})
+
+	entry := logrus.NewEntry(logrus.New())
+	entry.Message = "message"
+	entry.Level = logrus.InfoLevel
+	entry.Time = time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	entry.Caller = &runtime.Frame{File: "/src/main.go", Line: 10, Function: "main.main"}
+	entry.Data = logrus.Fields{
+		"tag":    fieldTag,
+		"zebra":  1,
+		"apple":  map[string]interface{}{"z": 1, "b": []interface{}{map[string]int{"y": 1, "x": 2}}, "a": 3},
+		"mango":  &benchRequest{ID: "id", Method: "GET", Status: 200},
+		"banana": map[int]string{3: "c", 1: "a", 2: "b"},
+	}
+	_, data := hook.getTagAndData(entry)
+
+	// the hook's own fields first, and then the others sorted.
+	direct, err := hook.converter.appendRecord(nil, data, hook.ownFieldKeys())
+	a.NoError(err)
+	a.Equal([]string{"level", "message", "time", "file", "line", "func", "apple", "banana", "mango", "zebra"}, readMapKeys(t, direct))
+
+	// the same entry is always encoded into the same bytes.
+	for i := 0; i < 20; i++ {
+		_, data := hook.getTagAndData(entry)
+		b, err := hook.converter.appendRecord(nil, data, hook.ownFieldKeys())
+		a.NoError(err)
+		a.Equal(direct, b)
+
+		// the converted map is encoded into the same bytes.
+		b, err = hook.orderRecord(hook.encodeData(data)).(msgp.Marshaler).MarshalMsg(nil)
+		a.NoError(err)
+		a.Equal(direct, b)
+	}
+
+	// compatible with the forward protocol.
+	msg := fluent.Message{Tag: fieldTag, Time: 1, Record: hook.orderRecord(hook.encodeData(data))}
+	b, err := msg.MarshalMsg(nil)
+	a.NoError(err)
+	var decoded fluent.Message
+	_, err = decoded.UnmarshalMsg(b)
+	a.NoError(err)
+	a.Equal(decodeMsgpack(t, direct), decoded.Record)
+}
+
+func TestKeyOrderNestedBytes(t *testing.T) {
+	a := assert.New(t)
+
+	c := Converter{TagName: TagName, KeyOrder: KeyOrderSorted}
+	b, err := c.AppendMsgpack(nil, map[string]interface{}{
+		"b": map[string]int{"d": 1, "c": 2},
+		"a": []interface{}{logrus.Fields{"f": 1, "e": 2}},
+	})
+	a.NoError(err)
+
+	expected := msgp.AppendMapHeader(nil, 2)
+	expected = msgp.AppendString(expected, "a")
+	expected = msgp.AppendArrayHeader(expected, 1)
+	expected = msgp.AppendMapHeader(expected, 2)
+	expected = msgp.AppendString(expected, "e")
+	expected = msgp.AppendInt(expected, 2)
+	expected = msgp.AppendString(expected, "f")
+	expected = msgp.AppendInt(expected, 1)
+	expected = msgp.AppendString(expected, "b")
+	expected = msgp.AppendMapHeader(expected, 2)
+	expected = msgp.AppendString(expected, "c")
+	expected = msgp.AppendInt(expected, 2)
+	expected = msgp.AppendString(expected, "d")
+	expected = msgp.AppendInt(expected, 1)
+	a.Equal(expected, b)
+
+	// the same keys are kept when truncated.
+	c.MaxCollectionSize = 2
+	m := map[string]int{"d": 1, "a": 2, "c": 3, "b": 4}
+	a.Equal(map[string]interface{}{"m": map[string]interface{}{"a": 2, "b": 4, TruncatedMarkerKey: truncatedMarker(2)}}, c.ConvertToValue(map[string]interface{}{"m": m}))
+	b, err = c.AppendMsgpack(nil, []interface{}{m})
+	a.NoError(err)
+	_, b, err = msgp.ReadArrayHeaderBytes(b)
+	a.NoError(err)
+	a.Equal([]string{"a", "b", TruncatedMarkerKey}, readMapKeys(t, b))
+}
+
+func TestOrderedRecordJSON(t *testing.T) {
+	a := assert.New(t)
+
+	r := newOrderedRecord(Converter{}, map[string]interface{}{
+		"z":       map[string]interface{}{"b": 1, "a": 2},
+		"message": "msg",
+		"a":       "\"quoted\"",
+		"level":   "info",
+	}, []string{"level", "message"})
+	b, err := json.Marshal(r)
+	a.NoError(err)
+	a.Equal(`{"level":"info","message":"msg","a":"\"quoted\"","z":{"a":2,"b":1}}`, string(b))
+}
+
+func TestSetKeyOrder(t *testing.T) {
+	a := assert.New(t)
+
+	hook := NewHook(testHOST, -1)
+	data := map[string]interface{}{"a": 1}
+	a.Equal(data, hook.orderRecord(data))
+
+	hook.SetKeyOrder(KeyOrderSorted)
+	a.Equal(KeyOrderSorted, hook.converter.KeyOrder)
+	a.IsType(&orderedRecord{}, hook.orderRecord(data))
+	a.Equal("str", hook.orderRecord("str"))
+
+	hook, err := NewWithConfig(Config{DisableConnectionPool: true, KeyOrder: KeyOrderSorted})
+	a.NoError(err)
+	a.Equal(KeyOrderSorted, hook.converter.KeyOrder)
+}
@@ -0,0 +1,41 @@
+package logrus_fluent
+
+// FieldPreset is a set of field names for the well-known log schema.
+type FieldPreset struct {
+	LevelField   string
+	MessageField string
+	TimeField    string
+	RenameFields map[string]string
+}
+
+var (
+	// PresetECS is field names for Elastic Common Schema.
+	// see https://www.elastic.co/guide/en/ecs/current/ecs-log.html
+	PresetECS = FieldPreset{
+		LevelField:   "log.level",
+		MessageField: "message",
+		TimeField:    "@timestamp",
+		RenameFields: map[string]string{
+			"error":  "error.message",
+			"logger": "log.logger",
+		},
+	}
+
+	// PresetOpenTelemetry is field names for OpenTelemetry Logs Data Model.
+	// see https://opentelemetry.io/docs/specs/otel/logs/data-model/
+	PresetOpenTelemetry = FieldPreset{
+		LevelField:   "severity_text",
+		MessageField: "body",
+		TimeField:    "timestamp",
+	}
+)
+
+// UseFieldPreset sets field names from the preset.
+func (hook *FluentHook) UseFieldPreset(p FieldPreset) {
+	hook.levelField = p.LevelField
+	hook.messageField = p.MessageField
+	hook.timeField = p.TimeField
+	for k, v := range p.RenameFields {
+		hook.AddRename(k, v)
+	}
+}
@@ -0,0 +1,44 @@
+package logrus_fluent
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUseFieldPreset(t *testing.T) {
+	a := assert.New(t)
+
+	hook := NewHook(testHOST, -1)
+	hook.UseFieldPreset(PresetECS)
+	a.Equal("log.level", hook.levelField)
+	a.Equal("message", hook.messageField)
+	a.Equal("@timestamp", hook.timeField)
+	a.Equal("error.message", hook.renameFields["error"])
+
+	entry := logrus.NewEntry(logrus.New())
+	entry.Data = logrus.Fields{"tag": fieldTag, "error": "unknown error"}
+	entry.Message = entryMessage
+	entry.Level = logrus.ErrorLevel
+
+	_, data := hook.getTagAndData(entry)
+	a.Equal("error", data["log.level"])
+	a.Equal(entryMessage, data["message"])
+	a.Equal("unknown error", data["error.message"])
+	a.Contains(data, "@timestamp")
+}
+
+func TestNewWithConfigFieldPreset(t *testing.T) {
+	a := assert.New(t)
+
+	hook, err := NewWithConfig(Config{
+		DisableConnectionPool: true,
+		DefaultFieldPreset:    &PresetOpenTelemetry,
+		DefaultMessageField:   "msg",
+	})
+	a.NoError(err)
+	a.Equal("severity_text", hook.levelField)
+	a.Equal("timestamp", hook.timeField)
+	a.Equal("msg", hook.messageField, "DefaultMessageField should overwrite the preset")
+}
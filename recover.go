@@ -0,0 +1,69 @@
+package logrus_fluent
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// field names for the panic log.
+const (
+	PanicField = "panic"
+	StackField = "stack"
+)
+
+// RecoverAndLog recovers panic and sends the log with the stack trace through the hook synchronously.
+// The panic is raised again after logging unless SetSwallowPanic(true) is used.
+// This must be called by defer directly.
+//
+//	go func() {
+//	    defer hook.RecoverAndLog(logger, logrus.Fields{"worker": "foo"})
+//	    ...
+//	}()
+func (hook *FluentHook) RecoverAndLog(logger *logrus.Logger, fields logrus.Fields) {
+	r := recover()
+	if r == nil {
+		return
+	}
+
+	if err := hook.firePanic(logger, fields, r); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to send panic log to fluentd: %v\n", err)
+	}
+	if !hook.swallowPanic {
+		panic(r)
+	}
+}
+
+// SetSwallowPanic sets whether RecoverAndLog swallows the panic or not.
+func (hook *FluentHook) SetSwallowPanic(swallow bool) {
+	hook.swallowPanic = swallow
+}
+
+// firePanic sends the panic log with the stack trace.
+func (hook *FluentHook) firePanic(logger *logrus.Logger, fields logrus.Fields, r interface{}) error {
+	entry := logrus.NewEntry(logger).WithFields(fields).WithFields(logrus.Fields{
+		PanicField: r,
+		StackField: stackFrames(panicCallers(), 0),
+	})
+	entry.Level = logrus.PanicLevel
+	entry.Time = time.Now()
+	entry.Message = fmt.Sprint(r)
+	return hook.Fire(entry)
+}
+
+// panicCallers returns program counters of the panicked goroutine
+// except the frames in this package and runtime panic functions.
+func panicCallers() []uintptr {
+	pcs := callers()
+	for i, pc := range pcs {
+		f, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+		if !strings.HasPrefix(f.Function, "runtime.") {
+			return pcs[i:]
+		}
+	}
+	return pcs
+}
@@ -0,0 +1,75 @@
+package logrus_fluent
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func panicWorker(hook *FluentHook, logger *logrus.Logger) {
+	defer hook.RecoverAndLog(logger, logrus.Fields{
+		"tag":    fieldTag,
+		"worker": "panicWorker",
+	})
+	panic("worker is down")
+}
+
+func TestRecoverAndLog(t *testing.T) {
+	a := assert.New(t)
+
+	localData := make(chan string)
+	_, port := newMockServer(t, localData)
+	hook := NewHook(testHOST, port)
+	logger := logrus.New()
+
+	// swallow
+	hook.SetSwallowPanic(true)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		panicWorker(hook, logger)
+	}()
+	result := <-localData
+	a.Contains(result, assertFieldTagAsFluentTag)
+	a.Contains(result, "\xa5level\xa5panic")
+	a.Contains(result, "\xa5panic\xaeworker is down")
+	a.Contains(result, "\xa6worker\xabpanicWorker")
+	a.Contains(result, "logrus_fluent.panicWorker")
+	a.Contains(result, "\xa5stack")
+
+	// the first worker reads the setting after sending the log.
+	<-done
+
+	// raise panic again
+	hook.SetSwallowPanic(false)
+	recovered := make(chan interface{})
+	go func() {
+		defer func() {
+			recovered <- recover()
+		}()
+		panicWorker(hook, logger)
+	}()
+	result = <-localData
+	a.Contains(result, "\xa5panic\xaeworker is down")
+	a.Equal("worker is down", <-recovered)
+}
+
+func TestPanicCallers(t *testing.T) {
+	a := assert.New(t)
+
+	var frames []interface{}
+	func() {
+		defer func() {
+			recover()
+			frames = stackFrames(panicCallers(), 0)
+		}()
+		var m map[string]int
+		m["nil map"] = 1
+	}()
+
+	a.NotEmpty(frames)
+	fn := frames[0].(map[string]interface{})["function"].(string)
+	a.False(strings.HasPrefix(fn, "runtime."), "runtime frames should be skipped: %s", fn)
+}
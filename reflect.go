@@ -1,145 +1,252 @@
 package logrus_fluent
 
 import (
-	"fmt"
 	"reflect"
 	"strings"
+	"time"
 )
 
+// Converter converts any value to the value for fluentd logger.
+type Converter struct {
+	TagName      string       // struct tag name. default is "fluent".
+	TagNames     []string     // struct tag names in priority order. (e.g. ["fluent", "json"]) TagName is used if empty.
+	ErrorEncoder ErrorEncoder // error is converted to err.Error() if nil.
+
+	// Marshalers are checked before reflection in priority order.
+	// DefaultMarshalers is used if nil, and no marshaler is used if empty.
+	Marshalers []Marshaler
+
+	TimeEncoding     TimeEncoding
+	TimeLayout       string         // layout for TimeString. default is RFC3339Nano.
+	TimeLocation     *time.Location // location for TimeString. the original location is used if nil.
+	DurationEncoding DurationEncoding
+	BytesEncoding    BytesEncoding
+	KeyOrder         KeyOrder
+
+	MaxDepth          int // max depth of nested values. default is 32.
+	MaxCollectionSize int // max number of items in a nested map or a slice. the top level value is not truncated. unlimited if 0.
+}
+
+// newConverter returns Converter from the hook config.
+func newConverter(conf Config) Converter {
+	return Converter{
+		TagName:          TagName,
+		TagNames:         conf.TagNames,
+		ErrorEncoder:     conf.ErrorEncoder,
+		Marshalers:       conf.Marshalers,
+		TimeEncoding:     conf.TimeEncoding,
+		TimeLayout:       conf.TimeLayout,
+		TimeLocation:     conf.TimeLocation,
+		DurationEncoding: conf.DurationEncoding,
+		BytesEncoding:    conf.BytesEncoding,
+		KeyOrder:         conf.KeyOrder,
+
+		MaxDepth:          conf.MaxDepth,
+		MaxCollectionSize: conf.MaxCollectionSize,
+	}
+}
+
 // ConvertToValue make map data from struct and tags
 func ConvertToValue(p interface{}, tagName string) interface{} {
+	c := Converter{TagName: tagName}
+	return c.ConvertToValue(p)
+}
+
+// ConvertToValue make map data from struct and tags
+func (c Converter) ConvertToValue(p interface{}) interface{} {
+	return c.convert(p, nil)
+}
+
+// newConvertState returns convertState with the tag names.
+// It's created lazily when the converter reaches a nested value.
+func (c Converter) newConvertState() *convertState {
+	tagNames := c.TagNames
+	switch {
+	case len(tagNames) != 0:
+	case c.TagName != "":
+		tagNames = []string{c.TagName}
+	default:
+		tagNames = []string{TagName} // zero value
+	}
+	return &convertState{tagNames: strings.Join(tagNames, ",")}
+}
+
+// convert converts the value. st is nil until a nested value is found.
+func (c Converter) convert(p interface{}, st *convertState) interface{} {
+	// fast path for common types
+	switch p.(type) {
+	case nil, string, int, int64, bool, float64:
+		return p
+	}
+	if v, ok := c.marshalFluent(p, st); ok {
+		return v
+	}
+	return c.convertValue(p, st)
+}
+
+// convertValue converts the value without FluentMarshaler.
+func (c Converter) convertValue(p interface{}, st *convertState) interface{} {
+	if v, ok := c.encodeTimeValue(p); ok {
+		return v
+	}
+
 	rv := toValue(p)
+	if rv.Kind() == reflect.Struct {
+		if err, ok := p.(error); ok {
+			return c.encodeError(err)
+		}
+	}
+	if v, ok := c.marshal(p); ok {
+		return v
+	}
+
 	switch rv.Kind() {
 	case reflect.Struct:
-		if err, ok := p.(error); ok {
-			return err.Error()
+		// nested value
+	case reflect.Map, reflect.Slice:
+		if rv.IsNil() {
+			return nil
 		}
-		return convertFromStruct(rv.Interface(), tagName)
-	case reflect.Map:
-		return convertFromMap(rv, tagName)
-	case reflect.Slice:
-		return convertFromSlice(rv, tagName)
-	case reflect.Chan:
-		return nil
-	case reflect.Invalid:
+		if isBytes(rv) {
+			return c.encodeBytes(rv)
+		}
+	case reflect.Array:
+		if isBytes(rv) {
+			return c.encodeBytes(rv)
+		}
+	case reflect.Invalid, reflect.Chan, reflect.Func, reflect.UnsafePointer:
 		return nil
+	case reflect.Ptr:
+		return MaxDepthMarker // too many indirections
 	default:
 		return rv.Interface()
 	}
+
+	if st == nil {
+		st = c.newConvertState()
+	}
+	if st.depth >= c.maxDepth() {
+		return MaxDepthMarker
+	}
+	key, ok := getVisitKey(reflect.ValueOf(p))
+	if ok {
+		if !st.visit(key) {
+			return CycleMarker
+		}
+		defer st.leave(key)
+	}
+	st.depth++
+	defer func() { st.depth-- }()
+
+	switch rv.Kind() {
+	case reflect.Struct:
+		return c.convertFromStruct(rv, st)
+	case reflect.Map:
+		return c.convertFromMap(rv, st)
+	default:
+		return c.convertFromSlice(rv, st)
+	}
 }
 
-func convertFromMap(rv reflect.Value, tagName string) interface{} {
+func (c Converter) encodeError(err error) interface{} {
+	if c.ErrorEncoder == nil {
+		return err.Error()
+	}
+	return c.ErrorEncoder(err)
+}
+
+func (c Converter) convertFromMap(rv reflect.Value, st *convertState) interface{} {
+	max := c.maxCollectionSize(st)
+	if c.KeyOrder == KeyOrderSorted && max > 0 && rv.Len() > max {
+		// keep the first keys in order, so the same keys are truncated every time.
+		keys, values := c.sortedMapEntries(rv)
+		result := make(map[string]interface{}, max+1)
+		for _, k := range keys[:max] {
+			result[k] = c.convert(values[k], st)
+		}
+		result[TruncatedMarkerKey] = truncatedMarker(len(keys) - max)
+		return result
+	}
+
 	result := make(map[string]interface{})
-	for _, key := range rv.MapKeys() {
+	for i, key := range rv.MapKeys() {
+		if max > 0 && i >= max {
+			result[TruncatedMarkerKey] = truncatedMarker(rv.Len() - i)
+			break
+		}
 		kv := rv.MapIndex(key)
-		result[fmt.Sprint(key.Interface())] = ConvertToValue(kv.Interface(), tagName)
+		result[c.mapKey(key)] = c.convert(kv.Interface(), st)
 	}
 	return result
 }
 
-func convertFromSlice(rv reflect.Value, tagName string) interface{} {
-	var result []interface{}
-	for i, max := 0, rv.Len(); i < max; i++ {
-		result = append(result, ConvertToValue(rv.Index(i).Interface(), tagName))
+func (c Converter) convertFromSlice(rv reflect.Value, st *convertState) interface{} {
+	max := c.maxCollectionSize(st)
+	size := rv.Len()
+	if max > 0 && size > max {
+		size = max + 1 // items and the truncated marker
+	}
+	result := make([]interface{}, 0, size)
+	for i, n := 0, rv.Len(); i < n; i++ {
+		if max > 0 && i >= max {
+			result = append(result, truncatedMarker(n-i))
+			break
+		}
+		result = append(result, c.convert(rv.Index(i).Interface(), st))
 	}
 	return result
 }
 
 // convertFromStruct converts struct to value
 // see: https://github.com/fatih/structs/
-func convertFromStruct(p interface{}, tagName string) interface{} {
+func (c Converter) convertFromStruct(rv reflect.Value, st *convertState) interface{} {
 	result := make(map[string]interface{})
-	return convertFromStructDeep(result, tagName, toType(p), toValue(p))
+	c.convertFromStructDeep(result, rv, st)
+	return result
 }
 
-func convertFromStructDeep(result map[string]interface{}, tagName string, t reflect.Type, values reflect.Value) interface{} {
-	for i, max := 0, t.NumField(); i < max; i++ {
-		f := t.Field(i)
-		if f.PkgPath != "" && !f.Anonymous {
-			continue
-		}
-
-		if f.Anonymous {
-			tt := f.Type
-			if tt.Kind() == reflect.Ptr {
-				tt = tt.Elem()
+func (c Converter) convertFromStructDeep(result map[string]interface{}, values reflect.Value, st *convertState) {
+	enc := getStructEncoder(values.Type(), st.tagNames)
+	for _, f := range enc.fields {
+		v := values.Field(f.index)
+		if f.embedded {
+			key, ok := getVisitKey(v)
+			if ok && !st.visit(key) {
+				continue // skip embedded pointer to itself
 			}
-			vv := values.Field(i)
-			if !vv.IsValid() {
-				continue
+			if v.Kind() == reflect.Ptr {
+				v = v.Elem()
 			}
-			if vv.Kind() == reflect.Ptr {
-				vv = vv.Elem()
+			if v.Kind() == reflect.Struct {
+				c.convertFromStructDeep(result, v, st)
 			}
-
-			if vv.Kind() == reflect.Struct {
-				convertFromStructDeep(result, tagName, tt, vv)
+			if ok {
+				st.leave(key)
 			}
 			continue
 		}
 
-		tag, opts := parseTag(f, tagName)
-		if tag == "-" {
-			continue // skip `-` tag
+		if f.isEmpty(v) {
+			continue // skip zero-value when omitempty option exists in tag
 		}
-
-		if !values.IsValid() {
+		if f.asString {
+			result[f.name] = toJSONString(v)
 			continue
 		}
-		v := values.Field(i)
-		if opts.Has("omitempty") && isZero(v) {
-			continue // skip zero-value when omitempty option exists in tag
-		}
-		name := getNameFromTag(f, tagName)
-		result[name] = ConvertToValue(v.Interface(), TagName)
+		result[f.name] = c.convert(v.Interface(), st)
 	}
-	return result
 }
 
-// toValue converts any value to reflect.Value
+// toValue converts any value to reflect.Value.
+// Pointers are dereferenced, and nil pointer is converted to invalid value.
 func toValue(p interface{}) reflect.Value {
 	v := reflect.ValueOf(p)
-	if v.Kind() == reflect.Ptr {
+	for i := 0; v.Kind() == reflect.Ptr && i < defaultMaxDepth; i++ {
 		v = v.Elem()
 	}
 	return v
 }
 
-// toType converts any value to reflect.Type
-func toType(p interface{}) reflect.Type {
-	t := reflect.ValueOf(p).Type()
-	if t.Kind() == reflect.Ptr {
-		t = t.Elem()
-	}
-	return t
-}
-
-// isZero checks the value is zero-value or not
-func isZero(v reflect.Value) bool {
-	zero := reflect.Zero(v.Type()).Interface()
-	value := v.Interface()
-	return reflect.DeepEqual(value, zero)
-}
-
-// getNameFromTag return the value in tag or field name in the struct field
-func getNameFromTag(f reflect.StructField, tagName string) string {
-	tag, _ := parseTag(f, tagName)
-	if tag != "" {
-		return tag
-	}
-	return f.Name
-}
-
-// getTagValues returns tag value of the struct field
-func getTagValues(f reflect.StructField, tag string) string {
-	return f.Tag.Get(tag)
-}
-
-// parseTag returns the first tag value of the struct field
-func parseTag(f reflect.StructField, tag string) (string, options) {
-	return splitTags(getTagValues(f, tag))
-}
-
 // splitTags returns the first tag value and rest slice
 func splitTags(tags string) (string, options) {
 	res := strings.Split(tags, ",")
@@ -158,3 +265,8 @@ func (t options) Has(tag string) bool {
 	}
 	return false
 }
+
+// SetTagNames sets struct tag names in priority order. (e.g. "fluent", "json")
+func (hook *FluentHook) SetTagNames(tagNames ...string) {
+	hook.converter.TagNames = tagNames
+}
@@ -92,3 +92,193 @@ func TestConvertToValueNil(t *testing.T) {
 	result = ConvertToValue(ptr, TagName)
 	assert.Equal(nil, result)
 }
+
+type byteAlias uint8
+
+func TestConvertToValueMatrix(t *testing.T) {
+	a := assert.New(t)
+
+	var (
+		nilPtr   *Creature
+		nilSlice []string
+		nilMap   map[string]int
+		nilBytes []byte
+		nilIface error
+		nilFunc  func()
+		nilChan  chan int
+	)
+	num := 1
+	numPtr := &num
+	hash := [4]byte{0xde, 0xad, 0xbe, 0xef}
+
+	tests := []struct {
+		name     string
+		value    interface{}
+		expected interface{}
+		base64   interface{}
+		hex      interface{}
+	}{
+		{name: "nil", value: nil},
+		{name: "nil pointer", value: nilPtr},
+		{name: "nil slice", value: nilSlice},
+		{name: "nil map", value: nilMap},
+		{name: "nil bytes", value: nilBytes},
+		{name: "nil interface", value: nilIface},
+		{name: "nil func", value: nilFunc},
+		{name: "func", value: func() {}},
+		{name: "nil chan", value: nilChan},
+		{name: "chan", value: make(chan int)},
+		{name: "int", value: 1, expected: 1},
+		{name: "pointer", value: numPtr, expected: 1},
+		{name: "pointer to pointer", value: &numPtr, expected: 1},
+		{name: "empty slice", value: []string{}, expected: []interface{}{}},
+		{name: "slice", value: []string{"a"}, expected: []interface{}{"a"}},
+		{name: "empty map", value: map[string]int{}, expected: map[string]interface{}{}},
+		{name: "array", value: [2]string{"a", "b"}, expected: []interface{}{"a", "b"}},
+		{name: "empty array", value: [0]int{}, expected: []interface{}{}},
+		{name: "array of nil", value: [1]*Creature{}, expected: []interface{}{nil}},
+		{name: "interface slice", value: []interface{}{nil, nilPtr, 1}, expected: []interface{}{nil, nil, 1}},
+		{
+			name: "bytes", value: []byte("abc"), expected: []byte("abc"),
+			base64: "YWJj", hex: "616263",
+		},
+		{
+			name: "empty bytes", value: []byte{}, expected: []byte{},
+			base64: "", hex: "",
+		},
+		{
+			name: "byte array", value: hash, expected: []byte{0xde, 0xad, 0xbe, 0xef},
+			base64: "3q2+7w==", hex: "deadbeef",
+		},
+		{
+			name: "byte array pointer", value: &hash, expected: []byte{0xde, 0xad, 0xbe, 0xef},
+			base64: "3q2+7w==", hex: "deadbeef",
+		},
+		{
+			name: "named byte slice", value: []byteAlias{1, 2}, expected: []byte{1, 2},
+			base64: "AQI=", hex: "0102",
+		},
+		{
+			name: "nested", value: map[string]interface{}{"s": nilSlice, "b": []byte("a"), "p": nilPtr},
+			expected: map[string]interface{}{"s": nil, "b": []byte("a"), "p": nil},
+			base64:   map[string]interface{}{"s": nil, "b": "YQ==", "p": nil},
+			hex:      map[string]interface{}{"s": nil, "b": "61", "p": nil},
+		},
+	}
+
+	for _, tt := range tests {
+		a.Equal(tt.expected, ConvertToValue(tt.value, TagName), tt.name)
+
+		base64, hex := tt.base64, tt.hex
+		if base64 == nil && hex == nil {
+			base64, hex = tt.expected, tt.expected
+		}
+		a.Equal(base64, Converter{TagName: TagName, BytesEncoding: BytesBase64}.ConvertToValue(tt.value), tt.name)
+		a.Equal(hex, Converter{TagName: TagName, BytesEncoding: BytesHex}.ConvertToValue(tt.value), tt.name)
+	}
+}
+
+func TestConvertToValueBytesCopy(t *testing.T) {
+	a := assert.New(t)
+
+	b := []byte("abc")
+	result := ConvertToValue(b, TagName)
+	b[0] = 'x'
+	a.Equal([]byte("abc"), result)
+}
+
+type benchRequest struct {
+	ID        string `fluent:"id"`
+	Method    string `fluent:"method"`
+	Path      string `fluent:"path"`
+	UserAgent string `fluent:"user_agent,omitempty"`
+	Referer   string `fluent:"referer,omitempty"`
+	Status    int    `fluent:"status"`
+	Size      int64  `fluent:"size,omitempty"`
+	Secret    string `fluent:"-"`
+	Headers   map[string]string
+	*benchUser
+}
+
+type benchUser struct {
+	UserID   int64  `fluent:"user_id"`
+	UserName string `fluent:"user_name,omitempty"`
+}
+
+func BenchmarkConvertToValueStruct(b *testing.B) {
+	v := &benchRequest{
+		ID:        "req-1",
+		Method:    "GET",
+		Path:      "/api/v1/items",
+		UserAgent: "Mozilla/5.0",
+		Status:    200,
+		Headers:   map[string]string{"Accept": "*/*"},
+		benchUser: &benchUser{UserID: 1},
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ConvertToValue(v, TagName)
+	}
+}
+
+type jsonOwner struct {
+	ID      int     `json:"id,string"`
+	Name    string  `json:"name"`
+	Nick    string  `fluent:"nickname" json:"nick"`
+	Hidden  string  `json:"-"`
+	Dash    string  `json:"-,"`
+	Empty   string  `json:"empty,omitempty"`
+	Zero    jsonPet `json:"zero,omitempty"` // struct is not omitted by encoding/json
+	Active  bool    `json:"active,string"`
+	NoTag   string
+	Pets    []jsonPet  `json:"pets"`
+	Partner *jsonOwner `json:"partner,omitempty"`
+}
+
+type jsonPet struct {
+	Name string `json:"pet_name"`
+}
+
+func TestConvertToValueTagNames(t *testing.T) {
+	assert := assert.New(t)
+
+	v := jsonOwner{
+		ID:     10,
+		Name:   "alice",
+		Nick:   "ally",
+		Hidden: "hidden",
+		Dash:   "dash",
+		NoTag:  "no tag",
+		Pets:   []jsonPet{{Name: "tama"}},
+	}
+
+	c := Converter{TagNames: []string{TagName, "json"}}
+	assert.Equal(map[string]interface{}{
+		"id":       "10",
+		"name":     "alice",
+		"nickname": "ally",
+		"-":        "dash",
+		"zero":     map[string]interface{}{"pet_name": ""},
+		"active":   "false",
+		"NoTag":    "no tag",
+		"pets":     []interface{}{map[string]interface{}{"pet_name": "tama"}},
+	}, c.ConvertToValue(v))
+
+	// nested fields use the same tag name.
+	result := ConvertToValue(map[string]interface{}{"owner": v}, "json").(map[string]interface{})
+	owner := result["owner"].(map[string]interface{})
+	assert.Equal("ally", owner["nick"])
+	assert.Equal([]interface{}{map[string]interface{}{"pet_name": "tama"}}, owner["pets"])
+
+	// the zero value uses the default tag name.
+	type tagged struct {
+		A string `fluent:"a_name"`
+	}
+	assert.Equal(map[string]interface{}{"a_name": "a"}, Converter{}.ConvertToValue(tagged{A: "a"}))
+	assert.Equal(ConvertToValue(tagged{A: "a"}, TagName), Converter{}.ConvertToValue(tagged{A: "a"}))
+	b, err := Converter{}.AppendMsgpack(nil, tagged{A: "a"})
+	assert.NoError(err)
+	assert.Equal(map[string]interface{}{"a_name": "a"}, decodeMsgpack(t, b))
+}
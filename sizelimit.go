@@ -0,0 +1,413 @@
+package logrus_fluent
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/tinylib/msgp/msgp"
+)
+
+// TruncatedField is field name for the list of truncated paths.
+const TruncatedField = "_truncated"
+
+// OversizeField is field name for the original size of the record which is replaced with the minimal record.
+const OversizeField = "_oversize"
+
+// ErrRecordTooLarge is returned by Fire when the record exceeds MaxRecordSize after truncation.
+// The minimal record is sent instead.
+var ErrRecordTooLarge = errors.New("logrus_fluent: record is too large")
+
+// markerSlack is the max length of the truncated bytes marker.
+const markerSlack = len("...(truncated  bytes)") + 20
+
+// maxTruncatedPaths is the max number of the paths in TruncatedField.
+const maxTruncatedPaths = 32
+
+// SizeLimitConfig is settings for limiting the size of records.
+// The limits are enforced after conversion, and the paths of the truncated values are listed in TruncatedField.
+//
+//	{"body": "aaaa...(truncated 12345 bytes)", "_truncated": ["body"]}
+type SizeLimitConfig struct {
+	MaxRecordSize     int    // max bytes of the record in msgpack. unlimited if 0.
+	MaxStringSize     int    // max bytes of a string or a byte slice. unlimited if 0.
+	MaxCollectionSize int    // max number of items in a nested map or a slice. unlimited if 0.
+	TruncatedField    string // field name for the list of truncated paths. default is "_truncated".
+}
+
+// truncatedBytesMarker returns a marker for omitted bytes in a string.
+func truncatedBytesMarker(n int) string {
+	return "...(truncated " + strconv.Itoa(n) + " bytes)"
+}
+
+// sizeLimiter holds the state while limiting a record.
+type sizeLimiter struct {
+	conf      SizeLimitConfig
+	kept      map[string]struct{} // top-level fields which are not truncated.
+	paths     map[string]struct{}
+	pathBytes int // total length of the paths.
+	maxPath   int // max length of the paths.
+	leaves    []*limitLeaf
+}
+
+// limitLeaf is a string or a byte slice in the record, which can be shrunk to fit MaxRecordSize.
+type limitLeaf struct {
+	path    string
+	value   interface{} // original string or []byte
+	kept    int         // kept bytes
+	encoded int         // msgpack size of the current value in the record.
+	set     func(v interface{})
+}
+
+// size returns the original size of the leaf.
+func (l *limitLeaf) size() int {
+	if s, ok := l.value.(string); ok {
+		return len(s)
+	}
+	return len(l.value.([]byte))
+}
+
+// truncated returns the value which keeps the first n bytes of the leaf.
+func (l *limitLeaf) truncated(n int) interface{} {
+	s, ok := l.value.(string)
+	if !ok {
+		l.kept = n
+		return l.value.([]byte)[:n]
+	}
+
+	// not to break a multibyte character
+	for n > 0 && n < len(s) && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	l.kept = n
+	return s[:n] + truncatedBytesMarker(len(s)-n)
+}
+
+// limit truncates the values in the data.
+// The fields in protected are not dropped even if the record is too large,
+// and the fields in kept are neither dropped nor truncated. (e.g. chunks)
+func (c SizeLimitConfig) limit(data map[string]interface{}, protected, kept []string) (map[string]interface{}, error) {
+	key := c.TruncatedField
+	if key == "" {
+		key = TruncatedField
+	}
+
+	l := &sizeLimiter{conf: c, paths: make(map[string]struct{})}
+	if len(kept) != 0 {
+		l.kept = make(map[string]struct{}, len(kept))
+		for _, k := range kept {
+			l.kept[k] = struct{}{}
+		}
+		protected = append(protected[:len(protected):len(protected)], kept...)
+	}
+	result := l.limitMap(data, "", false)
+	if c.MaxRecordSize > 0 {
+		l.shrink(result, key, protected)
+	}
+	l.setPaths(result, key)
+
+	if c.MaxRecordSize > 0 {
+		if n := msgpackSize(result); n > c.MaxRecordSize {
+			return l.minimal(result, key, protected, n), fmt.Errorf("%w: %d bytes > %d bytes", ErrRecordTooLarge, n, c.MaxRecordSize)
+		}
+	}
+	return result, nil
+}
+
+// shrink truncates the largest strings first, and then drops the largest fields except protected, to fit MaxRecordSize.
+// The record is measured once, and the size is tracked by the saved bytes and the added paths.
+func (l *sizeLimiter) shrink(result map[string]interface{}, key string, protected []string) {
+	max := l.conf.MaxRecordSize
+	size := msgpackSize(result) + l.pathsSize(key)
+
+	sort.SliceStable(l.leaves, func(i, j int) bool {
+		return l.leaves[i].kept > l.leaves[j].kept
+	})
+	for _, leaf := range l.leaves {
+		excess := size - max
+		if excess <= 0 {
+			return
+		}
+		// the marker and the path are added.
+		growth := l.pathsGrowth(key, leaf.path)
+		overhead := markerSlack + growth
+		if leaf.kept <= overhead {
+			continue // the marker is larger than the saved bytes.
+		}
+		n := leaf.kept - excess - overhead
+		if n < 0 {
+			n = 0
+		}
+		v := leaf.truncated(n)
+		leaf.set(v)
+		encoded := msgpackSize(v)
+		size += encoded - leaf.encoded + growth
+		leaf.encoded = encoded
+		l.addPath(leaf.path)
+	}
+
+	skip := map[string]struct{}{key: {}}
+	for _, k := range protected {
+		skip[k] = struct{}{}
+	}
+	fields := make([]string, 0, len(result))
+	sizes := make(map[string]int, len(result))
+	for k, v := range result {
+		if _, ok := skip[k]; !ok {
+			fields = append(fields, k)
+			sizes[k] = msgpackSize(v)
+		}
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		if sizes[fields[i]] != sizes[fields[j]] {
+			return sizes[fields[i]] > sizes[fields[j]]
+		}
+		return fields[i] < fields[j]
+	})
+	for _, k := range fields {
+		if size <= max {
+			return
+		}
+		growth := l.pathsGrowth(key, k)
+		delete(result, k)
+		l.addPath(k)
+		size += growth - (msgp.StringPrefixSize + len(k) + sizes[k])
+	}
+}
+
+// minimal returns the record which has only the protected fields, the truncated paths and the original size.
+// It's sent instead of the record which is still too large after shrinking.
+func (l *sizeLimiter) minimal(result map[string]interface{}, key string, protected []string, size int) map[string]interface{} {
+	skip := map[string]struct{}{key: {}}
+	m := make(map[string]interface{}, len(protected)+2)
+	for _, k := range protected {
+		if v, ok := result[k]; ok {
+			m[k] = v
+			skip[k] = struct{}{}
+		}
+	}
+	for k := range result {
+		if _, ok := skip[k]; !ok {
+			l.addPath(k)
+		}
+	}
+	l.setPaths(m, key)
+	m[OversizeField] = size
+	return m
+}
+
+// addPath adds the path of the truncated value.
+func (l *sizeLimiter) addPath(path string) {
+	if _, ok := l.paths[path]; ok {
+		return
+	}
+	l.paths[path] = struct{}{}
+	l.pathBytes += len(path)
+	if len(path) > l.maxPath {
+		l.maxPath = len(path)
+	}
+}
+
+// pathsSize returns the upper bound of the size of the truncated paths field.
+func (l *sizeLimiter) pathsSize(key string) int {
+	return truncatedPathsSize(key, len(l.paths), l.pathBytes, l.maxPath)
+}
+
+// pathsGrowth returns the bytes added to the truncated paths field by the path.
+func (l *sizeLimiter) pathsGrowth(key, path string) int {
+	if _, ok := l.paths[path]; ok {
+		return 0
+	}
+	maxPath := l.maxPath
+	if len(path) > maxPath {
+		maxPath = len(path)
+	}
+	return truncatedPathsSize(key, len(l.paths)+1, l.pathBytes+len(path), maxPath) - l.pathsSize(key)
+}
+
+// truncatedPathsSize returns the upper bound of the size of the truncated paths field with n paths.
+// Only maxTruncatedPaths paths are listed, so the total length is bounded by the longest path.
+func truncatedPathsSize(key string, n, pathBytes, maxPath int) int {
+	if n == 0 {
+		return 0
+	}
+	size := msgp.StringPrefixSize + len(key) + msgp.ArrayHeaderSize
+	if n <= maxTruncatedPaths {
+		return size + n*msgp.StringPrefixSize + pathBytes
+	}
+	if limit := maxTruncatedPaths * maxPath; pathBytes > limit {
+		pathBytes = limit
+	}
+	return size + (maxTruncatedPaths+1)*msgp.StringPrefixSize + pathBytes + len(truncatedMarker(n-maxTruncatedPaths))
+}
+
+// setPaths sets the list of the truncated paths into the record.
+// The list is limited to maxTruncatedPaths, not to make the record larger.
+func (l *sizeLimiter) setPaths(result map[string]interface{}, key string) {
+	if len(l.paths) == 0 {
+		return
+	}
+
+	paths := make([]string, 0, len(l.paths))
+	for p := range l.paths {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	if len(paths) > maxTruncatedPaths {
+		paths = append(paths[:maxTruncatedPaths], truncatedMarker(len(paths)-maxTruncatedPaths))
+	}
+	result[key] = paths
+}
+
+// limitMap copies the map with the limits. The top level map is not truncated by MaxCollectionSize.
+func (l *sizeLimiter) limitMap(m map[string]interface{}, path string, nested bool) map[string]interface{} {
+	keys := sortedKeys(m)
+	if nested && l.conf.MaxCollectionSize > 0 && len(keys) > l.conf.MaxCollectionSize {
+		keys = keys[:l.conf.MaxCollectionSize]
+		l.addPath(path)
+	}
+
+	result := make(map[string]interface{}, len(keys)+1)
+	for _, k := range keys {
+		k := k
+		if _, ok := l.kept[k]; ok && !nested {
+			result[k] = m[k]
+			continue
+		}
+		result[k] = l.limitValue(m[k], joinPath(path, k), func(v interface{}) { result[k] = v })
+	}
+	if len(keys) < len(m) {
+		result[TruncatedMarkerKey] = truncatedMarker(len(m) - len(keys))
+	}
+	return result
+}
+
+func (l *sizeLimiter) limitSlice(s []interface{}, path string) []interface{} {
+	size := len(s)
+	if l.conf.MaxCollectionSize > 0 && size > l.conf.MaxCollectionSize {
+		size = l.conf.MaxCollectionSize
+		l.addPath(path)
+	}
+
+	result := make([]interface{}, size, size+1)
+	for i := 0; i < size; i++ {
+		i := i
+		result[i] = l.limitValue(s[i], joinPath(path, strconv.Itoa(i)), func(v interface{}) { result[i] = v })
+	}
+	if size < len(s) {
+		result = append(result, truncatedMarker(len(s)-size))
+	}
+	return result
+}
+
+func (l *sizeLimiter) limitValue(v interface{}, path string, set func(interface{})) interface{} {
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		if vv == nil {
+			return vv
+		}
+		return l.limitMap(vv, path, true)
+	case []interface{}:
+		if vv == nil {
+			return vv
+		}
+		return l.limitSlice(vv, path)
+	case string, []byte:
+		leaf := &limitLeaf{path: path, value: vv, set: set}
+		leaf.kept = leaf.size()
+		l.leaves = append(l.leaves, leaf)
+		if max := l.conf.MaxStringSize; max > 0 && leaf.kept > max {
+			l.addPath(path)
+			t := leaf.truncated(max)
+			leaf.encoded = msgpackSize(t)
+			return t
+		}
+		leaf.encoded = msgpackSize(vv)
+		return vv
+	default:
+		return v
+	}
+}
+
+// joinPath returns the dotted path of the child.
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// msgpackSize returns the upper bound of the size of the converted value in msgpack.
+func msgpackSize(v interface{}) int {
+	switch vv := v.(type) {
+	case nil:
+		return msgp.NilSize
+	case bool:
+		return msgp.BoolSize
+	case string:
+		return msgp.StringPrefixSize + len(vv)
+	case []byte:
+		return msgp.BytesPrefixSize + len(vv)
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return msgp.Int64Size
+	case float32:
+		return msgp.Float32Size
+	case float64:
+		return msgp.Float64Size
+	case map[string]interface{}:
+		n := msgp.MapHeaderSize
+		for k, x := range vv {
+			n += msgp.StringPrefixSize + len(k) + msgpackSize(x)
+		}
+		return n
+	case []interface{}:
+		n := msgp.ArrayHeaderSize
+		for _, x := range vv {
+			n += msgpackSize(x)
+		}
+		return n
+	case []string:
+		n := msgp.ArrayHeaderSize
+		for _, s := range vv {
+			n += msgp.StringPrefixSize + len(s)
+		}
+		return n
+	}
+
+	rv := reflect.ValueOf(v)
+	if isBasicKind(rv.Kind()) {
+		b := appendBasic(nil, rv)
+		return len(b)
+	}
+	b, err := Converter{}.appendConverted(nil, v)
+	if err != nil {
+		return 0
+	}
+	return len(b)
+}
+
+// limitRecord applies the size limits to the converted record.
+func (hook *FluentHook) limitRecord(record interface{}) (interface{}, error) {
+	m, ok := record.(map[string]interface{})
+	if hook.sizeLimit == nil || !ok {
+		return record, nil
+	}
+	return hook.sizeLimit.limit(m, hook.ownFieldKeys(), hook.chunkKeptFields())
+}
+
+// chunkKeptFields returns the fields which must not be truncated not to break the chunks.
+func (hook *FluentHook) chunkKeptFields() []string {
+	if hook.chunk == nil {
+		return nil
+	}
+	return append([]string{ChunkIDField, ChunkFieldsField}, hook.chunk.fields(hook.messageField)...)
+}
+
+// SetSizeLimit sets the limits of the record size.
+// The limits are disabled when conf is nil.
+func (hook *FluentHook) SetSizeLimit(conf *SizeLimitConfig) {
+	hook.sizeLimit = conf
+}
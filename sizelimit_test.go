@@ -0,0 +1,220 @@
+package logrus_fluent
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/tinylib/msgp/msgp"
+)
+
+func TestSizeLimitStringAndCollection(t *testing.T) {
+	a := assert.New(t)
+
+	data := map[string]interface{}{
+		"short": "abc",
+		"long":  strings.Repeat("a", 20),
+		"multi": "ああああ", // 12 bytes
+		"bytes": []byte("0123456789abc"),
+		"user": map[string]interface{}{
+			"a": 1, "b": 2, "c": strings.Repeat("c", 11),
+		},
+		"list": []interface{}{1, 2, 3, 4},
+		"top1": 1, "top2": 2, "top3": 3, // the top level is not truncated by MaxCollectionSize
+	}
+	conf := SizeLimitConfig{MaxStringSize: 10, MaxCollectionSize: 3}
+	result, err := conf.limit(data, nil, nil)
+	a.NoError(err)
+	a.Equal(map[string]interface{}{
+		"short": "abc",
+		"long":  strings.Repeat("a", 10) + "...(truncated 10 bytes)",
+		"multi": "あああ...(truncated 3 bytes)",
+		"bytes": []byte("0123456789"),
+		"user": map[string]interface{}{
+			"a": 1, "b": 2, "c": strings.Repeat("c", 10) + "...(truncated 1 bytes)",
+		},
+		"list": []interface{}{1, 2, 3, truncatedMarker(1)},
+		"top1": 1, "top2": 2, "top3": 3,
+		TruncatedField: []string{"bytes", "list", "long", "multi", "user.c"},
+	}, result)
+
+	// the original data is not modified.
+	a.Equal(strings.Repeat("a", 20), data["long"])
+	a.Len(data["list"], 4)
+
+	// nested maps keep the first keys in order.
+	conf = SizeLimitConfig{MaxCollectionSize: 1, TruncatedField: "truncated"}
+	result, err = conf.limit(map[string]interface{}{
+		"list": []interface{}{map[string]interface{}{"b": 1, "a": 2}},
+	}, nil, nil)
+	a.NoError(err)
+	a.Equal(map[string]interface{}{
+		"list":      []interface{}{map[string]interface{}{"a": 2, TruncatedMarkerKey: truncatedMarker(1)}},
+		"truncated": []string{"list.0"},
+	}, result)
+
+	// nothing is added without truncation.
+	result, err = conf.limit(map[string]interface{}{"a": "b"}, nil, nil)
+	a.NoError(err)
+	a.Equal(map[string]interface{}{"a": "b"}, result)
+}
+
+func TestSizeLimitRecord(t *testing.T) {
+	a := assert.New(t)
+
+	conf := SizeLimitConfig{MaxRecordSize: 1000}
+	data := map[string]interface{}{
+		"message": "message",
+		"body":    strings.Repeat("b", 3000),
+		"request": map[string]interface{}{"payload": strings.Repeat("p", 500)},
+		"small":   "small",
+	}
+
+	// the largest string is truncated first.
+	result, err := conf.limit(data, []string{"message"}, nil)
+	a.NoError(err)
+	a.LessOrEqual(msgpackSize(result), 1000)
+	a.Equal([]string{"body"}, result[TruncatedField])
+	a.Contains(result["body"], "...(truncated ")
+	a.Equal(strings.Repeat("p", 500), result["request"].(map[string]interface{})["payload"])
+	a.Equal("small", result["small"])
+
+	// the estimated size is not smaller than the encoded size.
+	b, err := Converter{}.appendConverted(nil, result)
+	a.NoError(err)
+	a.LessOrEqual(len(b), msgpackSize(result))
+
+	// the fields are dropped when the strings are not enough.
+	many := map[string]interface{}{"message": "message"}
+	for i := 0; i < 30; i++ {
+		many[fmt.Sprintf("field%02d", i)] = strings.Repeat("v", 40)
+	}
+	result, err = conf.limit(many, []string{"message"}, nil)
+	a.NoError(err)
+	a.LessOrEqual(msgpackSize(result), 1000)
+	a.Equal("message", result["message"])
+	a.NotContains(result, "field00")
+	a.Equal(strings.Repeat("v", 40), result["field29"])
+	a.Contains(result[TruncatedField], "field00")
+
+	// the size is tracked without measuring the record at every step.
+	huge := map[string]interface{}{"message": "message"}
+	for i := 0; i < 8000; i++ {
+		huge[fmt.Sprintf("field%04d", i)] = "value"
+	}
+	result, err = SizeLimitConfig{MaxRecordSize: 1024}.limit(huge, []string{"message"}, nil)
+	a.NoError(err)
+	a.LessOrEqual(msgpackSize(result), 1024)
+	a.Equal("message", result["message"])
+	a.Len(result[TruncatedField], maxTruncatedPaths+1)
+
+	// the list of the paths is limited.
+	paths := map[string]interface{}{}
+	for i := 0; i < 100; i++ {
+		paths[fmt.Sprintf("field%02d", i)] = strings.Repeat("v", 20)
+	}
+	result, err = SizeLimitConfig{MaxStringSize: 10}.limit(paths, nil, nil)
+	a.NoError(err)
+	a.Len(result[TruncatedField], maxTruncatedPaths+1)
+	a.Equal(truncatedMarker(100-maxTruncatedPaths), result[TruncatedField].([]string)[maxTruncatedPaths])
+
+	// an error is returned when the hook's own fields are too large.
+	own := map[string]interface{}{}
+	for i := 0; i < 100; i++ {
+		own[strings.Repeat("k", i+1)] = i
+	}
+	protected := make([]string, 0, len(own))
+	for k := range own {
+		protected = append(protected, k)
+	}
+	result, err = conf.limit(own, protected, nil)
+	a.True(errors.Is(err, ErrRecordTooLarge))
+	a.Len(result, len(own)+1, "the minimal record has the protected fields")
+	a.Contains(result, OversizeField)
+
+	// the kept fields are neither truncated nor dropped.
+	chunk := map[string]interface{}{"message": strings.Repeat("m", 2000), "other": strings.Repeat("o", 2000)}
+	result, err = SizeLimitConfig{MaxRecordSize: 1000, MaxStringSize: 100}.limit(chunk, nil, []string{"message"})
+	a.True(errors.Is(err, ErrRecordTooLarge))
+	a.Equal(strings.Repeat("m", 2000), result["message"])
+	a.Equal([]string{"other"}, result[TruncatedField])
+	a.Contains(result, OversizeField)
+}
+
+func TestMsgpackSize(t *testing.T) {
+	a := assert.New(t)
+
+	values := []interface{}{
+		nil, true, "", strings.Repeat("a", 100000), []byte("bytes"),
+		-1, int64(1 << 40), uint8(255), float32(1.5), 2.5, color(1),
+		map[string]interface{}{"a": []interface{}{1, "b", map[string]interface{}{}}},
+		[]string{"a", "b"},
+	}
+	for _, v := range values {
+		b, err := msgp.AppendIntf(nil, v)
+		if err != nil {
+			b, err = Converter{}.appendConverted(nil, v)
+		}
+		a.NoError(err)
+		a.LessOrEqual(len(b), msgpackSize(v), "%#v", v)
+	}
+}
+
+func TestSetSizeLimit(t *testing.T) {
+	a := assert.New(t)
+
+	hook := NewHook(testHOST, -1)
+	hook.SetSizeLimit(&SizeLimitConfig{MaxStringSize: 5})
+
+	entry := logrus.NewEntry(logrus.New())
+	entry.Message = entryMessage
+	entry.Data = logrus.Fields{"tag": fieldTag, "body": "0123456789"}
+
+	_, data := hook.getTagAndData(entry)
+	record, err := hook.limitRecord(hook.encodeData(data))
+	a.NoError(err)
+	result, ok := record.(map[string]interface{})
+	a.True(ok)
+	a.Equal("01234...(truncated 5 bytes)", result["body"])
+	a.Equal([]string{"body", MessageField}, result[TruncatedField])
+
+	hook.SetSizeLimit(nil)
+	record, err = hook.limitRecord(hook.encodeData(data))
+	a.NoError(err)
+	a.Equal("0123456789", record.(map[string]interface{})["body"])
+
+	// the minimal record is returned with the error when the hook's own fields are too large.
+	hook.SetSizeLimit(&SizeLimitConfig{MaxRecordSize: 10})
+	record, err = hook.limitRecord(hook.encodeData(data))
+	a.True(errors.Is(err, ErrRecordTooLarge))
+	result = record.(map[string]interface{})
+	a.Equal(entryMessage, result[MessageField])
+	a.Equal("panic", result[LevelField])
+	a.Equal([]string{"body"}, result[TruncatedField])
+	a.Greater(result[OversizeField], 10)
+	a.Len(result, 4)
+}
+
+func TestFireRecordTooLarge(t *testing.T) {
+	a := assert.New(t)
+
+	received := make(chan string)
+	_, port := newMockServer(t, received)
+	hook := NewHook(testHOST, port)
+	hook.SetSizeLimit(&SizeLimitConfig{MaxRecordSize: 10})
+
+	entry := logrus.NewEntry(logrus.New())
+	entry.Message = entryMessage
+	entry.Data = logrus.Fields{"tag": fieldTag, "body": "0123456789"}
+
+	// the minimal record is sent, and the error is returned.
+	err := hook.Fire(entry)
+	a.True(errors.Is(err, ErrRecordTooLarge))
+	result := <-received
+	a.Contains(result, OversizeField)
+	a.Contains(result, entryMessage)
+	a.NotContains(result, "0123456789")
+}
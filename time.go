@@ -0,0 +1,128 @@
+package logrus_fluent
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/fluent/fluent-logger-golang/fluent"
+)
+
+// TimeEncoding is encoding type for time.Time.
+type TimeEncoding int
+
+const (
+	// TimeString encodes time to string with the layout. (default: RFC3339Nano)
+	TimeString TimeEncoding = iota
+	// TimeUnix encodes time to unix epoch seconds.
+	TimeUnix
+	// TimeUnixMilli encodes time to unix epoch milliseconds.
+	TimeUnixMilli
+	// TimeUnixNano encodes time to unix epoch nanoseconds.
+	TimeUnixNano
+	// TimeEventTime encodes time to fluentd EventTime in msgpack, and unix epoch nanoseconds in JSON.
+	TimeEventTime
+)
+
+// DurationEncoding is encoding type for time.Duration.
+type DurationEncoding int
+
+const (
+	// DurationNanoseconds encodes duration to nanoseconds. (default)
+	DurationNanoseconds DurationEncoding = iota
+	// DurationString encodes duration to string. (e.g. "1.5s")
+	DurationString
+	// DurationSeconds encodes duration to seconds as a float.
+	DurationSeconds
+)
+
+// encodeTime converts time.Time by TimeEncoding.
+func (c Converter) encodeTime(t time.Time) interface{} {
+	switch c.TimeEncoding {
+	case TimeUnix:
+		return t.Unix()
+	case TimeUnixMilli:
+		return t.UnixNano() / int64(time.Millisecond)
+	case TimeUnixNano:
+		return t.UnixNano()
+	case TimeEventTime:
+		return &eventTime{fluent.EventTime(t)}
+	default:
+		layout := c.TimeLayout
+		if layout == "" {
+			layout = time.RFC3339Nano
+		}
+		if c.TimeLocation != nil {
+			t = t.In(c.TimeLocation)
+		}
+		return t.Format(layout)
+	}
+}
+
+// encodeDuration converts time.Duration by DurationEncoding.
+func (c Converter) encodeDuration(d time.Duration) interface{} {
+	switch c.DurationEncoding {
+	case DurationString:
+		return d.String()
+	case DurationSeconds:
+		return d.Seconds()
+	default:
+		return int64(d)
+	}
+}
+
+// eventTime is fluent.EventTime which can be encoded in JSON too.
+// fluent.EventTime has no exported fields, so it's encoded to {} in JSON.
+type eventTime struct {
+	fluent.EventTime
+}
+
+// MarshalJSON implements json.Marshaler. It's encoded to unix epoch nanoseconds.
+func (t *eventTime) MarshalJSON() ([]byte, error) {
+	return strconv.AppendInt(nil, time.Time(t.EventTime).UnixNano(), 10), nil
+}
+
+// encodeTimeValue converts time.Time and time.Duration.
+// The encoded EventTime is returned as it is, because the hook's own time field is converted again.
+func (c Converter) encodeTimeValue(p interface{}) (interface{}, bool) {
+	switch v := p.(type) {
+	case time.Time:
+		return c.encodeTime(v), true
+	case *time.Time:
+		if v != nil {
+			return c.encodeTime(*v), true
+		}
+	case *eventTime:
+		if v != nil {
+			return v, true
+		}
+	case fluent.EventTime:
+		return c.encodeTime(time.Time(v)), true
+	case *fluent.EventTime:
+		if v != nil {
+			return c.encodeTime(time.Time(*v)), true
+		}
+	case time.Duration:
+		return c.encodeDuration(v), true
+	case *time.Duration:
+		if v != nil {
+			return c.encodeDuration(*v), true
+		}
+	}
+	return nil, false
+}
+
+// SetTimeEncoding sets encoding type for time.Time.
+func (hook *FluentHook) SetTimeEncoding(enc TimeEncoding) {
+	hook.converter.TimeEncoding = enc
+}
+
+// SetTimeLayout sets layout and location for TimeString encoding.
+func (hook *FluentHook) SetTimeLayout(layout string, loc *time.Location) {
+	hook.converter.TimeLayout = layout
+	hook.converter.TimeLocation = loc
+}
+
+// SetDurationEncoding sets encoding type for time.Duration.
+func (hook *FluentHook) SetDurationEncoding(enc DurationEncoding) {
+	hook.converter.DurationEncoding = enc
+}
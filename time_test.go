@@ -0,0 +1,124 @@
+package logrus_fluent
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/fluent/fluent-logger-golang/fluent"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConverterTime(t *testing.T) {
+	a := assert.New(t)
+
+	tm := time.Date(2020, 1, 2, 3, 4, 5, 6000000, time.UTC)
+	jst := time.FixedZone("JST", 9*60*60)
+	et := &eventTime{fluent.EventTime(tm)}
+
+	tests := []struct {
+		conf     Converter
+		expected interface{}
+	}{
+		{Converter{}, "2020-01-02T03:04:05.006Z"},
+		{Converter{TimeLayout: time.RFC1123, TimeLocation: jst}, "Thu, 02 Jan 2020 12:04:05 JST"},
+		{Converter{TimeEncoding: TimeUnix}, int64(1577934245)},
+		{Converter{TimeEncoding: TimeUnixMilli}, int64(1577934245006)},
+		{Converter{TimeEncoding: TimeUnixNano}, int64(1577934245006000000)},
+		{Converter{TimeEncoding: TimeEventTime}, et},
+	}
+
+	for _, tt := range tests {
+		a.Equal(tt.expected, tt.conf.ConvertToValue(tm), "%#v", tt.conf)
+		a.Equal(tt.expected, tt.conf.ConvertToValue(&tm), "%#v", tt.conf)
+		a.Equal(tt.expected, tt.conf.ConvertToValue(fluent.EventTime(tm)), "%#v", tt.conf)
+
+		result := tt.conf.ConvertToValue(struct{ CreatedAt time.Time }{tm})
+		a.Equal(map[string]interface{}{"CreatedAt": tt.expected}, result, "%#v", tt.conf)
+	}
+}
+
+func TestConverterDuration(t *testing.T) {
+	a := assert.New(t)
+
+	d := 1500 * time.Millisecond
+	tests := []struct {
+		enc      DurationEncoding
+		expected interface{}
+	}{
+		{DurationNanoseconds, int64(1500000000)},
+		{DurationString, "1.5s"},
+		{DurationSeconds, 1.5},
+	}
+
+	for _, tt := range tests {
+		c := Converter{DurationEncoding: tt.enc}
+		a.Equal(tt.expected, c.ConvertToValue(d), "%d", tt.enc)
+		a.Equal(map[string]interface{}{"elapsed": tt.expected}, c.ConvertToValue(map[string]interface{}{"elapsed": d}))
+	}
+}
+
+func TestSetTimeEncoding(t *testing.T) {
+	a := assert.New(t)
+
+	hook := NewHook(testHOST, -1)
+	hook.SetTimeField("time")
+	hook.SetTimeEncoding(TimeUnix)
+	hook.SetDurationEncoding(DurationString)
+
+	entry := logrus.NewEntry(logrus.New())
+	entry.Data = logrus.Fields{"tag": fieldTag, "elapsed": time.Second}
+	entry.Time = time.Unix(1577934245, 0)
+
+	_, data := hook.getTagAndData(entry)
+	result := hook.encodeData(data).(map[string]interface{})
+	a.Equal(int64(1577934245), result["time"])
+	a.Equal("1s", result["elapsed"])
+
+	hook.SetTimeEncoding(TimeString)
+	hook.SetTimeLayout("2006-01-02", time.UTC)
+	_, data = hook.getTagAndData(entry)
+	a.Equal("2020-01-02", data["time"])
+}
+
+func TestTimeEventTime(t *testing.T) {
+	a := assert.New(t)
+
+	hook := NewHook(testHOST, -1)
+	hook.SetTimeField("time")
+	hook.SetTimeEncoding(TimeEventTime)
+
+	tm := time.Unix(1577934245, 6)
+	entry := logrus.NewEntry(logrus.New())
+	entry.Data = logrus.Fields{"tag": fieldTag, "created": tm}
+	entry.Time = tm
+
+	_, data := hook.getTagAndData(entry)
+	record := hook.encodeData(data)
+	result := record.(map[string]interface{})
+	a.Equal(&eventTime{fluent.EventTime(tm)}, result["time"], "the hook's own time field is not converted again")
+	a.Equal(&eventTime{fluent.EventTime(tm)}, result["created"])
+
+	// EventTime extension in msgpack.
+	msg := fluent.Message{Tag: fieldTag, Time: 1, Record: record}
+	b, err := msg.MarshalMsg(nil)
+	a.NoError(err)
+	var decoded fluent.Message
+	_, err = decoded.UnmarshalMsg(b)
+	a.NoError(err)
+	a.IsType(&fluent.EventTime{}, decoded.Record.(map[string]interface{})["time"])
+
+	// direct encoding is the same.
+	direct, err := hook.converter.AppendMsgpack(nil, data)
+	a.NoError(err)
+	expected, err := hook.converter.appendConverted(nil, record)
+	a.NoError(err)
+	a.Equal(decodeMsgpack(t, expected), decodeMsgpack(t, direct))
+
+	// unix epoch nanoseconds in JSON.
+	js, err := json.Marshal(record)
+	a.NoError(err)
+	a.Contains(string(js), `"time":1577934245000000006`)
+	a.Contains(string(js), `"created":1577934245000000006`)
+}
@@ -0,0 +1,72 @@
+package logrus_fluent
+
+import (
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// default field names for OpenTelemetry trace correlation.
+const (
+	TraceIDKey    = "trace_id"
+	SpanIDKey     = "span_id"
+	TraceFlagsKey = "trace_flags"
+
+	spanEventName = "log"
+)
+
+// TraceConfig is settings for OpenTelemetry trace correlation.
+// The active span is read from entry.Context.
+type TraceConfig struct {
+	TraceIDKey    string // default is "trace_id".
+	SpanIDKey     string // default is "span_id".
+	TraceFlagsKey string // default is "trace_flags".
+	SpanEvent     bool   // the log is recorded as an event of the span if true.
+}
+
+// SetTraceCorrelation sets settings for OpenTelemetry trace correlation.
+// Trace correlation is disabled when conf is nil.
+func (hook *FluentHook) SetTraceCorrelation(conf *TraceConfig) {
+	if conf == nil {
+		hook.trace = nil
+		return
+	}
+
+	c := *conf
+	if c.TraceIDKey == "" {
+		c.TraceIDKey = TraceIDKey
+	}
+	if c.SpanIDKey == "" {
+		c.SpanIDKey = SpanIDKey
+	}
+	if c.TraceFlagsKey == "" {
+		c.TraceFlagsKey = TraceFlagsKey
+	}
+	hook.trace = &c
+}
+
+// setTrace adds trace id and span id of the active span in W3C hex format,
+// and records the log as a span event if enabled.
+func (hook *FluentHook) setTrace(entry *logrus.Entry, data logrus.Fields) {
+	c := hook.trace
+	if c == nil || entry.Context == nil {
+		return
+	}
+
+	span := trace.SpanFromContext(entry.Context)
+	sc := span.SpanContext()
+	if !sc.IsValid() {
+		return
+	}
+
+	data[c.TraceIDKey] = sc.TraceID().String()
+	data[c.SpanIDKey] = sc.SpanID().String()
+	data[c.TraceFlagsKey] = sc.TraceFlags().String()
+
+	if c.SpanEvent && span.IsRecording() {
+		span.AddEvent(spanEventName, trace.WithTimestamp(entry.Time), trace.WithAttributes(
+			attribute.String("log.severity", entry.Level.String()),
+			attribute.String("log.message", entry.Message),
+		))
+	}
+}
@@ -0,0 +1,56 @@
+package logrus_fluent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSetTraceCorrelation(t *testing.T) {
+	a := assert.New(t)
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	ctx, span := provider.Tracer("test").Start(context.Background(), "operation")
+
+	hook := NewHook(testHOST, -1)
+	hook.SetTraceCorrelation(&TraceConfig{
+		TraceIDKey: "trace.id",
+		SpanEvent:  true,
+	})
+
+	entry := logrus.NewEntry(logrus.New()).WithContext(ctx).WithField("tag", fieldTag)
+	entry.Level = logrus.ErrorLevel
+	entry.Message = entryMessage
+
+	_, data := hook.getTagAndData(entry)
+	sc := span.SpanContext()
+	a.Equal(sc.TraceID().String(), data["trace.id"])
+	a.Equal(sc.SpanID().String(), data[SpanIDKey])
+	a.Equal("01", data[TraceFlagsKey])
+	a.Len(sc.TraceID().String(), 32)
+	a.Len(sc.SpanID().String(), 16)
+	a.NotContains(data, TraceIDKey)
+
+	span.End()
+	spans := recorder.Ended()
+	a.Len(spans, 1)
+	events := spans[0].Events()
+	a.Len(events, 1)
+	a.Equal("log", events[0].Name)
+	a.Contains(events[0].Attributes, attribute.String("log.message", entryMessage))
+	a.Contains(events[0].Attributes, attribute.String("log.severity", "error"))
+
+	// without span
+	entry = logrus.NewEntry(logrus.New()).WithContext(context.Background()).WithField("tag", fieldTag)
+	_, data = hook.getTagAndData(entry)
+	a.NotContains(data, SpanIDKey)
+
+	hook.SetTraceCorrelation(nil)
+	a.Nil(hook.trace)
+}